@@ -2,8 +2,11 @@ package cli
 
 import (
     "fmt"
+    "net"
     "os"
+    "strconv"
 
+    "github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
     "github.com/spf13/cobra"
     "github.com/spf13/viper"
     "go.uber.org/zap"
@@ -34,6 +37,8 @@ func Execute() {
 func init() {
     cobra.OnInitialize(initConfig)
 
+    rootCmd.AddCommand(rawCmd)
+    rootCmd.AddCommand(setupCmd)
     rootCmd.AddCommand(testCmd)
 }
 
@@ -57,3 +62,22 @@ func initConfig() {
         // os.Exit(1)
     }
 }
+
+// parseTarget turns a 'host' or 'host:port' argument into a device config.
+func parseTarget(target string) (devices.DeviceConfig, error) {
+    host, port := target, uint16(devices.DefaultPort)
+
+    if h, p, err := net.SplitHostPort(target); err == nil {
+        value, err := strconv.ParseUint(p, 10, 16)
+        if err != nil {
+            return devices.DeviceConfig{}, fmt.Errorf("invalid port in '%s'", target)
+        }
+        host, port = h, uint16(value)
+    }
+
+    if host == "" {
+        return devices.DeviceConfig{}, fmt.Errorf("invalid target '%s'", target)
+    }
+
+    return devices.NewDeviceConfig(host, devices.WithPort(port)), nil
+}
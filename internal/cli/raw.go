@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/tplink"
+	"github.com/spf13/cobra"
+)
+
+type rawCommandArgs struct {
+	request string
+	target  string
+	timeout time.Duration
+}
+
+var (
+	rawArgs rawCommandArgs
+
+	rawCmd = &cobra.Command{
+		Long: "Send a raw JSON command to a device and pretty-print the reply, " +
+			"for example:\n\n" +
+			"  tplink-cli raw 10.0.0.5 '{\"cnCloud\":{\"get_info\":{}}}'",
+		Short: "Send a raw JSON command to a device.",
+		Use:   "raw <target> '<json>'",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmdArgs := rawArgs
+			cmdArgs.target = args[0]
+			cmdArgs.request = args[1]
+			return runRawCmd(cmd, &cmdArgs)
+		},
+	}
+)
+
+func init() {
+	rawCmd.Flags().DurationVar(&rawArgs.timeout, "timeout", 30*time.Second,
+		"time to wait for the device to reply")
+}
+
+func runRawCmd(cmd *cobra.Command, args *rawCommandArgs) error {
+	config, err := parseTarget(args.target)
+	if err != nil {
+		return err
+	}
+
+	manager := devices.NewDeviceManager(
+		devices.WithLogger(rootLogger))
+	api := tplink.NewDeviceManager(manager)
+
+	ctx, cancel := context.WithTimeout(context.Background(), args.timeout)
+	defer cancel()
+
+	res, err := api.RawContext(ctx, devices.NewDevice(&config), args.request)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(out))
+	return err
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/network"
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/tplink"
+	"github.com/spf13/cobra"
+)
+
+type setupCommandArgs struct {
+	keyType  int
+	password string
+	scan     bool
+	ssid     string
+	target   string
+	timeout  time.Duration
+	wait     time.Duration
+}
+
+var (
+	setupArgs setupCommandArgs
+
+	setupCmd = &cobra.Command{
+		Long: "Connect a factory-reset device to a wireless network. Join the " +
+			"access point of the device first, then run for example:\n\n" +
+			"  tplink-cli setup --scan\n" +
+			"  tplink-cli setup Home --password secret --wait 2m",
+		Short: "Connect a device in setup mode to a wireless network.",
+		Use:   "setup [ssid]",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmdArgs := setupArgs
+			if len(args) > 0 {
+				cmdArgs.ssid = args[0]
+			}
+			if cmdArgs.ssid == "" && !cmdArgs.scan {
+				return fmt.Errorf("a network name is required unless --scan is given")
+			}
+			return runSetupCmd(cmd, &cmdArgs)
+		},
+	}
+)
+
+func init() {
+	flags := setupCmd.Flags()
+	flags.IntVar(&setupArgs.keyType, "key-type", network.KeyTypeAuto,
+		"key type of the network (0 none, 1 WEP, 2 WPA, 3 WPA2), detected by default")
+	flags.StringVar(&setupArgs.password, "password", "",
+		"password of the network")
+	flags.BoolVar(&setupArgs.scan, "scan", false,
+		"only list the networks visible to the device")
+	flags.StringVar(&setupArgs.target, "target", network.SetupAddress,
+		"address of the device in setup mode")
+	flags.DurationVar(&setupArgs.timeout, "timeout", 30*time.Second,
+		"time to wait for the device to reply")
+	flags.DurationVar(&setupArgs.wait, "wait", 0,
+		"time to look for the device on the network afterwards, 0 does not wait")
+}
+
+func runSetupCmd(cmd *cobra.Command, args *setupCommandArgs) error {
+	config, err := parseTarget(args.target)
+	if err != nil {
+		return err
+	}
+
+	manager := devices.NewDeviceManager(
+		devices.WithLogger(rootLogger))
+	api := tplink.NewDeviceManager(manager)
+	device := devices.NewDevice(&config)
+
+	// The wait for the device on the target network comes on top of the
+	// time the device needs to reply.
+	ctx, cancel := context.WithTimeout(context.Background(), args.timeout+args.wait)
+	defer cancel()
+
+	out := cmd.OutOrStdout()
+
+	if args.scan {
+		networks, err := api.ScanNetworksContext(ctx, device, true)
+		if err != nil {
+			return err
+		}
+		for _, ap := range networks {
+			_, _ = fmt.Fprintf(out, "%-32s %s\n", ap.SSID, keyTypeName(ap.KeyType))
+		}
+		return nil
+	}
+
+	mgr := network.NewManager(network.WithLogger(rootLogger))
+	result, err := mgr.OnboardContext(ctx, api, device, args.ssid, args.password,
+		network.WithKeyType(args.keyType),
+		network.WithWaitTimeout(args.wait))
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(out, "Device %s joined '%s' (%s)\n",
+		result.Info.DeviceId, args.ssid, keyTypeName(result.KeyType))
+
+	if result.Device != nil {
+		_, _ = fmt.Fprintf(out, "Device found at %s:%d\n",
+			result.Device.Config.Address, result.Device.Config.Port)
+	}
+	return nil
+}
+
+func keyTypeName(keyType int) string {
+	switch keyType {
+	case tplink.KeyTypeNone:
+		return "open"
+	case tplink.KeyTypeWEP:
+		return "WEP"
+	case tplink.KeyTypeWPA:
+		return "WPA"
+	case tplink.KeyTypeWPA2:
+		return "WPA2"
+	}
+	return fmt.Sprintf("key type %d", keyType)
+}
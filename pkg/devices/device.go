@@ -1,5 +1,9 @@
 package devices
 
+import (
+    "sync"
+)
+
 type DeviceType int
 
 const (
@@ -75,8 +79,18 @@ type Addressable interface {
     Port() uint16
 }
 
+// ChildAddressable is implemented by outlets of a power strip. Requests to a
+// child are sent to the address of its parent and name the outlet by id.
+type ChildAddressable interface {
+    Addressable
+    ChildId() string
+}
+
 type Device struct {
     address     string
+    alias       string
+    childId     string
+    children    []*Device
     deviceId    string
     deviceName  string
 	deviceType  DeviceType
@@ -84,16 +98,30 @@ type Device struct {
     firmwareId  string
     hardwareId  string
     hardwareVer string
+    latitude    float64
+    ledOn       bool
+    longitude   float64
     modelVer    string
+    mutex       sync.RWMutex
     oemId       string
+    onTime      int
+    parent      *Device
     port        uint16
+    relayState  bool
     softwareVer string
 }
 
 var (
-    _ Addressable = (*Device)(nil)
+    _ Addressable      = (*Device)(nil)
+    _ ChildAddressable = (*Device)(nil)
 )
 
+func WithAlias(alias string) DeviceOption {
+    return func(d *Device) {
+        d.alias = alias
+    }
+}
+
 func WithDeviceId(deviceId string) DeviceOption {
     return func(d *Device) {
         d.deviceId = deviceId
@@ -131,6 +159,19 @@ func WithHardwareVersion(hardwareVer string) DeviceOption {
     }
 }
 
+func WithLedState(on bool) DeviceOption {
+    return func(d *Device) {
+        d.ledOn = on
+    }
+}
+
+func WithLocation(latitude float64, longitude float64) DeviceOption {
+    return func(d *Device) {
+        d.latitude = latitude
+        d.longitude = longitude
+    }
+}
+
 func WithModelVersion(modelVer string) DeviceOption {
     return func(d *Device) {
         d.modelVer = modelVer
@@ -143,6 +184,18 @@ func WithManufacturerId(oemId string) DeviceOption {
     }
 }
 
+func WithOnTime(onTime int) DeviceOption {
+    return func(d *Device) {
+        d.onTime = onTime
+    }
+}
+
+func WithRelayState(relayState bool) DeviceOption {
+    return func(d *Device) {
+        d.relayState = relayState
+    }
+}
+
 func WithSoftwareVersion(softwareVer string) DeviceOption {
     return func(d *Device) {
         d.softwareVer = softwareVer
@@ -162,16 +215,86 @@ func NewDevice(cfg *DeviceConfig, options ...DeviceOption) *Device {
     return device
 }
 
+// NewChildDevice creates an outlet of the parent device and adds it to the
+// children of the parent. The outlet shares the address and the hardware
+// details of the parent.
+func NewChildDevice(parent *Device, childId string, options ...DeviceOption) *Device {
+    parent.mutex.RLock()
+    child := &Device{
+        address:     parent.address,
+        childId:     childId,
+        deviceId:    childId,
+        deviceName:  parent.deviceName,
+        deviceType:  parent.deviceType,
+        features:    parent.features,
+        firmwareId:  parent.firmwareId,
+        hardwareId:  parent.hardwareId,
+        hardwareVer: parent.hardwareVer,
+        modelVer:    parent.modelVer,
+        oemId:       parent.oemId,
+        parent:      parent,
+        port:        parent.port,
+        softwareVer: parent.softwareVer}
+    parent.mutex.RUnlock()
+
+    for _, option := range options {
+        option(child)
+    }
+
+    parent.mutex.Lock()
+    parent.children = append(parent.children, child)
+    parent.mutex.Unlock()
+
+    return child
+}
+
 func (d *Device) Address() string {
     return d.address
 }
 
+func (d *Device) Alias() string {
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return d.alias
+}
+
+// Apply updates the cached fields of the device, for example after a
+// setting was changed on the device. It is safe to call while other
+// goroutines read the device.
+func (d *Device) Apply(options ...DeviceOption) {
+    d.mutex.Lock()
+    defer d.mutex.Unlock()
+
+    for _, option := range options {
+        option(d)
+    }
+}
+
+// ChildId is the id of the outlet, empty unless the device is a child.
+func (d *Device) ChildId() string {
+    return d.childId
+}
+
+func (d *Device) Children() []*Device {
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return append([]*Device(nil), d.children...)
+}
+
 func (d *Device) DeviceId() string {
-	return d.deviceId
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return d.deviceId
 }
 
 func (d *Device) DeviceName() string {
-	return d.deviceName
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return d.deviceName
 }
 
 func (d *Device) DeviceType() DeviceType {
@@ -179,33 +302,86 @@ func (d *Device) DeviceType() DeviceType {
 }
 
 func (d *Device) Features() []string {
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
     return d.features
 }
 
 func (d *Device) FirmwareId() string {
-	return d.firmwareId
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return d.firmwareId
 }
 
 func (d *Device) HardwareId() string {
-	return d.hardwareId
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return d.hardwareId
 }
 
 func (d *Device) HardwareVersion() string {
-	return d.hardwareVer
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return d.hardwareVer
+}
+
+func (d *Device) LedOn() bool {
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return d.ledOn
+}
+
+func (d *Device) Location() (float64, float64) {
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return d.latitude, d.longitude
 }
 
 func (d *Device) ManufacturerId() string {
-	return d.oemId
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return d.oemId
 }
 
 func (d *Device) Model() string {
-	return d.modelVer
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return d.modelVer
+}
+
+func (d *Device) OnTime() int {
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return d.onTime
+}
+
+func (d *Device) Parent() *Device {
+    return d.parent
 }
 
 func (d *Device) Port() uint16 {
     return d.port
 }
 
+func (d *Device) RelayState() bool {
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return d.relayState
+}
+
 func (d *Device) SoftwareVersion() string {
-	return d.softwareVer
+    d.mutex.RLock()
+    defer d.mutex.RUnlock()
+
+    return d.softwareVer
 }
@@ -1,8 +1,10 @@
 package devices
 
 import (
-    "go.uber.org/zap"
+    "sync"
     "time"
+
+    "go.uber.org/zap"
 )
 
 type DeviceManagerOption func(*DeviceManagerOptions)
@@ -10,7 +12,16 @@ type DeviceManagerOption func(*DeviceManagerOptions)
 type DeviceManagerOptions struct {
     ConnectTimeout time.Duration
     Logger         *zap.Logger
-    RecvTimeout    time.Duration
+
+    // MaxConnections limits the number of devices being talked to at the
+    // same time across the manager. Zero means no limit.
+    MaxConnections int
+
+    // MinCommandInterval is the minimum time between the end of one command
+    // and the start of the next one to the same device.
+    MinCommandInterval time.Duration
+
+    RecvTimeout time.Duration
 }
 
 func WithLogger(logger *zap.Logger) DeviceManagerOption {
@@ -19,6 +30,18 @@ func WithLogger(logger *zap.Logger) DeviceManagerOption {
     }
 }
 
+func WithMaxConnections(connections int) DeviceManagerOption {
+    return func(dm *DeviceManagerOptions) {
+        dm.MaxConnections = connections
+    }
+}
+
+func WithMinCommandInterval(interval time.Duration) DeviceManagerOption {
+    return func(dm *DeviceManagerOptions) {
+        dm.MinCommandInterval = interval
+    }
+}
+
 func DefaultDeviceManagerOptions() *DeviceManagerOptions {
     return &DeviceManagerOptions{
         ConnectTimeout: 60 * time.Second,
@@ -27,9 +50,13 @@ func DefaultDeviceManagerOptions() *DeviceManagerOptions {
 }
 
 type DeviceManager struct {
+    connections    chan struct{}
     connectTimeout time.Duration
     devices        []*Device
     logger         *zap.Logger
+    minInterval    time.Duration
+    mutex          sync.Mutex
+    queues         map[string]*deviceQueue
     recvTimeout    time.Duration
 }
 
@@ -46,8 +73,14 @@ func NewDeviceManager(opts ...DeviceManagerOption) *DeviceManager {
     mgr := new(DeviceManager)
     mgr.connectTimeout = options.ConnectTimeout
     mgr.logger = options.Logger
+    mgr.minInterval = options.MinCommandInterval
+    mgr.queues = make(map[string]*deviceQueue)
     mgr.recvTimeout = options.RecvTimeout
 
+    if options.MaxConnections > 0 {
+        mgr.connections = make(chan struct{}, options.MaxConnections)
+    }
+
     return mgr
 }
 
@@ -57,7 +90,10 @@ func (dm *DeviceManager) Logger() *zap.Logger {
 
 func (dm *DeviceManager) NewDevice(cfg *DeviceConfig, options ...DeviceOption) *Device {
     device := NewDevice(cfg, options...)
+
+    dm.mutex.Lock()
     dm.devices = append(dm.devices, device)
+    dm.mutex.Unlock()
 
     return device
 }
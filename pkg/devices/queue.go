@@ -0,0 +1,112 @@
+package devices
+
+import (
+    "context"
+    "fmt"
+    "sync"
+    "time"
+)
+
+// deviceQueue serializes the commands sent to a single device. Cheap
+// firmware drops connections when two commands arrive at once.
+type deviceQueue struct {
+    key      string
+    lock     chan struct{}
+    lastSent time.Time
+    waiters  int
+}
+
+// queue returns the queue of the device and registers the caller as one of
+// its waiters, the caller must call release once it is done with it.
+func (dm *DeviceManager) queue(d Addressable) *deviceQueue {
+    key := fmt.Sprintf("%s:%d", d.Address(), d.Port())
+
+    dm.mutex.Lock()
+    defer dm.mutex.Unlock()
+
+    q, ok := dm.queues[key]
+    if !ok {
+        dm.pruneQueues()
+
+        q = &deviceQueue{key: key, lock: make(chan struct{}, 1)}
+        dm.queues[key] = q
+    }
+    q.waiters++
+
+    return q
+}
+
+// release unregisters a waiter of the queue. sent records that a command was
+// sent to the device.
+func (dm *DeviceManager) release(q *deviceQueue, sent bool) {
+    dm.mutex.Lock()
+    defer dm.mutex.Unlock()
+
+    if sent {
+        q.lastSent = time.Now()
+    }
+    q.waiters--
+
+    if q.waiters == 0 && dm.minInterval <= 0 {
+        delete(dm.queues, q.key)
+    }
+}
+
+// pruneQueues removes the queues without waiters whose last command is more
+// than MinCommandInterval ago, they no longer delay the next command. The
+// caller must hold the mutex.
+func (dm *DeviceManager) pruneQueues() {
+    for key, q := range dm.queues {
+        if q.waiters == 0 && time.Since(q.lastSent) >= dm.minInterval {
+            delete(dm.queues, key)
+        }
+    }
+}
+
+// Acquire waits until a command may be sent to the device. Commands to the
+// same device are sent one at a time, at least MinCommandInterval apart, and
+// no more than MaxConnections devices are talked to at once. The returned
+// function must be called once the command has completed.
+func (dm *DeviceManager) Acquire(ctx context.Context, d Addressable) (func(), error) {
+    q := dm.queue(d)
+
+    select {
+    case q.lock <- struct{}{}:
+    case <-ctx.Done():
+        dm.release(q, false)
+        return nil, ctx.Err()
+    }
+
+    if wait := dm.minInterval - time.Since(q.lastSent); !q.lastSent.IsZero() && wait > 0 {
+        timer := time.NewTimer(wait)
+        select {
+        case <-timer.C:
+        case <-ctx.Done():
+            timer.Stop()
+            <-q.lock
+            dm.release(q, false)
+            return nil, ctx.Err()
+        }
+    }
+
+    if dm.connections != nil {
+        select {
+        case dm.connections <- struct{}{}:
+        case <-ctx.Done():
+            <-q.lock
+            dm.release(q, false)
+            return nil, ctx.Err()
+        }
+    }
+
+    var once sync.Once
+    return func() {
+        once.Do(func() {
+            if dm.connections != nil {
+                <-dm.connections
+            }
+            dm.release(q, true)
+            <-q.lock
+        })
+    }, nil
+}
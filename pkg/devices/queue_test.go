@@ -0,0 +1,177 @@
+package devices
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testAddress struct {
+	address string
+}
+
+func (a testAddress) Address() string {
+	return a.address
+}
+
+func (a testAddress) Port() uint16 {
+	return DefaultPort
+}
+
+// runCommands sends count commands per device concurrently and returns the
+// highest number of commands in flight at once, overall and per device.
+func runCommands(t *testing.T, dm *DeviceManager, addresses []string, count int) (int32, int32) {
+	var active, maxActive, maxDevice int32
+	perDevice := make(map[string]*int32)
+	for _, address := range addresses {
+		perDevice[address] = new(int32)
+	}
+
+	update := func(max *int32, value int32) {
+		for {
+			current := atomic.LoadInt32(max)
+			if value <= current || atomic.CompareAndSwapInt32(max, current, value) {
+				return
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, address := range addresses {
+		for i := 0; i < count; i++ {
+			wg.Add(1)
+			go func(address string) {
+				defer wg.Done()
+
+				release, err := dm.Acquire(context.Background(), testAddress{address})
+				if err != nil {
+					t.Errorf("failed to acquire device: %s", err)
+					return
+				}
+				defer release()
+
+				update(&maxActive, atomic.AddInt32(&active, 1))
+				update(&maxDevice, atomic.AddInt32(perDevice[address], 1))
+
+				time.Sleep(5 * time.Millisecond)
+
+				atomic.AddInt32(perDevice[address], -1)
+				atomic.AddInt32(&active, -1)
+			}(address)
+		}
+	}
+	wg.Wait()
+
+	return maxActive, maxDevice
+}
+
+func TestAcquireSerializesDevice(t *testing.T) {
+	dm := NewDeviceManager()
+
+	_, maxDevice := runCommands(t, dm, []string{"10.0.0.1", "10.0.0.2"}, 10)
+	if maxDevice != 1 {
+		t.Fatalf("%d commands were sent to the same device at once", maxDevice)
+	}
+}
+
+func TestAcquireMaxConnections(t *testing.T) {
+	dm := NewDeviceManager(WithMaxConnections(2))
+
+	addresses := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"}
+	maxActive, maxDevice := runCommands(t, dm, addresses, 4)
+	if maxActive > 2 {
+		t.Fatalf("%d connections were open at once", maxActive)
+	}
+	if maxDevice != 1 {
+		t.Fatalf("%d commands were sent to the same device at once", maxDevice)
+	}
+}
+
+func TestAcquireMinCommandInterval(t *testing.T) {
+	dm := NewDeviceManager(WithMinCommandInterval(50 * time.Millisecond))
+	device := testAddress{"10.0.0.1"}
+
+	release, err := dm.Acquire(context.Background(), device)
+	if err != nil {
+		t.Fatalf("failed to acquire device: %s", err)
+	}
+	release()
+
+	start := time.Now()
+	if release, err = dm.Acquire(context.Background(), device); err != nil {
+		t.Fatalf("failed to acquire device: %s", err)
+	}
+	release()
+
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("second command was sent after %s", elapsed)
+	}
+
+	// Other devices are not delayed.
+	start = time.Now()
+	if release, err = dm.Acquire(context.Background(), testAddress{"10.0.0.2"}); err != nil {
+		t.Fatalf("failed to acquire device: %s", err)
+	}
+	release()
+
+	if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+		t.Fatalf("unrelated device was delayed by %s", elapsed)
+	}
+}
+
+func TestAcquireCancelled(t *testing.T) {
+	dm := NewDeviceManager()
+	device := testAddress{"10.0.0.1"}
+
+	release, err := dm.Acquire(context.Background(), device)
+	if err != nil {
+		t.Fatalf("failed to acquire device: %s", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err = dm.Acquire(ctx, device); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestAcquireEvictsIdleQueues(t *testing.T) {
+	dm := NewDeviceManager(WithMinCommandInterval(0))
+
+	addresses := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	runCommands(t, dm, addresses, 4)
+
+	if len(dm.queues) != 0 {
+		t.Fatalf("%d idle queues were kept", len(dm.queues))
+	}
+
+	// Queues are kept while they still delay the next command.
+	dm = NewDeviceManager(WithMinCommandInterval(20 * time.Millisecond))
+	for _, address := range addresses {
+		release, err := dm.Acquire(context.Background(), testAddress{address})
+		if err != nil {
+			t.Fatalf("failed to acquire device: %s", err)
+		}
+		release()
+	}
+	if len(dm.queues) != len(addresses) {
+		t.Fatalf("unexpected number of queues %d", len(dm.queues))
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	release, err := dm.Acquire(context.Background(), testAddress{"10.0.0.4"})
+	if err != nil {
+		t.Fatalf("failed to acquire device: %s", err)
+	}
+	release()
+
+	if len(dm.queues) != 1 {
+		t.Fatalf("%d queues remain after the interval elapsed", len(dm.queues))
+	}
+}
@@ -3,12 +3,29 @@ package devices
 import (
     "bytes"
     "context"
+    "encoding/binary"
     "errors"
     "fmt"
+    "io"
     "net"
     "time"
 )
 
+const (
+    DefaultMaxMessageSize uint32 = 1024 * 1024
+
+    // headerSize is the length of the big-endian message length which
+    // prefixes every message sent over TCP.
+    headerSize = 4
+)
+
+var (
+    ErrDecodingFailed   = errors.New("failed to decode message")
+    ErrEncodingFailed   = errors.New("failed to encode message")
+    ErrMessageTooLarge  = errors.New("message exceeds the maximum message size")
+    ErrMessageTruncated = errors.New("connection closed before the full message was received")
+)
+
 type DecodeFn func([]byte) (*bytes.Buffer, bool)
 type EncodeFn func([]byte) (*bytes.Buffer, bool)
 
@@ -21,23 +38,33 @@ func WithEncoding(decodeFn DecodeFn, encodeFn EncodeFn) SenderOption{
     }
 }
 
+// WithMaxMessageSize limits the size of a response payload. Responses that
+// announce a larger payload are rejected with ErrMessageTooLarge before
+// anything is buffered. A size of zero disables the limit.
+func WithMaxMessageSize(size uint32) SenderOption {
+    return func(s *SyncSender) {
+        s.maxMessageSize = size
+    }
+}
+
 func WithTimeout(timeout time.Duration) SenderOption {
     return func(s *SyncSender) {
         s.timeout = timeout
     }
 }
 
+// SyncSender is the default Transport. Each request is sent over a new TCP
+// connection and the response is read synchronously.
 type SyncSender struct {
-    ctx      context.Context
-    device   Addressable
-    decodeFn DecodeFn
-    encodeFn EncodeFn
-    timeout  time.Duration
+    decodeFn       DecodeFn
+    encodeFn       EncodeFn
+    maxMessageSize uint32
+    timeout        time.Duration
 }
 
-func NewSyncSender(d Addressable, options ...SenderOption) *SyncSender {
-    sender := &SyncSender{device: d}
-    sender.ctx = context.Background()
+func NewSyncSender(options ...SenderOption) *SyncSender {
+    sender := new(SyncSender)
+    sender.maxMessageSize = DefaultMaxMessageSize
 
     for _, opt := range options {
         opt(sender)
@@ -46,12 +73,15 @@ func NewSyncSender(d Addressable, options ...SenderOption) *SyncSender {
     return sender
 }
 
-func (s *SyncSender) Send(data []byte) ([]byte, error) {
-    var encoded *bytes.Buffer
+// Send delivers data to the device and waits for the response. The context
+// is honoured while connecting, writing and reading, a cancelled context is
+// reported as ctx.Err() rather than the underlying network error.
+func (s *SyncSender) Send(parent context.Context, d Addressable, data []byte) ([]byte, error) {
+    encoded := bytes.NewBuffer(data)
     if s.encodeFn != nil {
         var ok bool
         if encoded, ok = s.encodeFn(data); !ok {
-            return []byte{}, errors.New("")
+            return []byte{}, ErrEncodingFailed
         }
     }
 
@@ -59,43 +89,95 @@ func (s *SyncSender) Send(data []byte) ([]byte, error) {
     var cancelFn context.CancelFunc
 
     if s.timeout > 0 {
-        ctx, cancelFn = context.WithTimeout(s.ctx, s.timeout)
+        ctx, cancelFn = context.WithTimeout(parent, s.timeout)
     } else {
-        ctx, cancelFn = context.WithCancel(s.ctx)
+        ctx, cancelFn = context.WithCancel(parent)
     }
     defer cancelFn()
 
     var dialer net.Dialer
     address := fmt.Sprintf(
         "%s:%d",
-        s.device.Address(),
-        s.device.Port())
+        d.Address(),
+        d.Port())
 
     sock, err := dialer.DialContext(ctx, "tcp", address)
     if err != nil {
-        return []byte{}, err
+        return []byte{}, contextError(parent, err)
     }
 
     defer func() { _ = sock.Close() }()
 
+    if deadline, ok := ctx.Deadline(); ok {
+        if err = sock.SetDeadline(deadline); err != nil {
+            return []byte{}, err
+        }
+    }
+
+    // Unblock any pending write or read as soon as the context is done.
+    stop := context.AfterFunc(ctx, func() {
+        _ = sock.SetDeadline(time.Unix(1, 0))
+    })
+    defer stop()
+
     _, err = sock.Write(encoded.Bytes())
     if err != nil {
-        return []byte{}, err
+        return []byte{}, contextError(parent, err)
     }
 
-    buffer := make([]byte, 4096)
-    _, err = sock.Read(buffer)
-
+    buffer, err := s.readMessage(sock)
     if err != nil {
-        return []byte{}, err
+        return []byte{}, contextError(parent, err)
     }
 
     if s.decodeFn != nil {
         if buf, ok := s.decodeFn(buffer); ok {
             return buf.Bytes(), nil
         }
-        return []byte{}, errors.New("")
+        return []byte{}, ErrDecodingFailed
+    }
+
+    return buffer, nil
+}
+
+// readMessage reads a single length-prefixed message from r. The returned
+// buffer includes the length header so it can be handed to the DecodeFn
+// as-is.
+func (s *SyncSender) readMessage(r io.Reader) ([]byte, error) {
+    header := make([]byte, headerSize)
+    if n, err := io.ReadFull(r, header); err != nil {
+        return nil, truncated(err, n, headerSize)
+    }
+
+    length := binary.BigEndian.Uint32(header)
+    if s.maxMessageSize > 0 && length > s.maxMessageSize {
+        return nil, fmt.Errorf("%w: %d bytes announced, limit is %d",
+            ErrMessageTooLarge, length, s.maxMessageSize)
+    }
+
+    buffer := make([]byte, headerSize+int(length))
+    copy(buffer, header)
+
+    if n, err := io.ReadFull(r, buffer[headerSize:]); err != nil {
+        return nil, truncated(err, n, int(length))
     }
 
     return buffer, nil
 }
+
+// contextError prefers the error of a cancelled parent context over the
+// network error it caused.
+func contextError(ctx context.Context, err error) error {
+    if ctxErr := ctx.Err(); ctxErr != nil {
+        return ctxErr
+    }
+    return err
+}
+
+func truncated(err error, received int, expected int) error {
+    if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+        return fmt.Errorf("%w: received %d of %d bytes",
+            ErrMessageTruncated, received, expected)
+    }
+    return err
+}
@@ -0,0 +1,164 @@
+package devices
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+type mockServer struct {
+	listener net.Listener
+	port     uint16
+}
+
+// newMockServer accepts a single connection, reads the request and replies
+// with each chunk as a separate write before closing the connection.
+func newMockServer(t *testing.T, chunks ...[]byte) *mockServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen on loopback: %s", err)
+	}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		buf := make([]byte, 1024)
+		if _, err = conn.Read(buf); err != nil {
+			return
+		}
+
+		for _, chunk := range chunks {
+			if _, err = conn.Write(chunk); err != nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	return &mockServer{
+		listener: listener,
+		port:     uint16(listener.Addr().(*net.TCPAddr).Port),
+	}
+}
+
+func (s *mockServer) Address() string {
+	return "127.0.0.1"
+}
+
+func (s *mockServer) Port() uint16 {
+	return s.port
+}
+
+func (s *mockServer) Stop() {
+	_ = s.listener.Close()
+}
+
+func header(length uint32) []byte {
+	buf := make([]byte, headerSize)
+	binary.BigEndian.PutUint32(buf, length)
+	return buf
+}
+
+func TestSyncSenderLargeMessage(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789"), 2000)
+
+	server := newMockServer(t,
+		header(uint32(len(payload))),
+		payload[:5000],
+		payload[5000:12000],
+		payload[12000:])
+	defer server.Stop()
+
+	sender := NewSyncSender(WithTimeout(5*time.Second))
+	res, err := sender.Send(context.Background(), server, []byte("request"))
+	if err != nil {
+		t.Fatalf("failed to send message: %s", err)
+	}
+
+	if !bytes.Equal(res[headerSize:], payload) {
+		t.Fatalf("received %d bytes, expected %d", len(res)-headerSize, len(payload))
+	}
+}
+
+func TestSyncSenderTruncatedMessage(t *testing.T) {
+	server := newMockServer(t, header(100), bytes.Repeat([]byte("x"), 10))
+	defer server.Stop()
+
+	sender := NewSyncSender(WithTimeout(5*time.Second))
+	if _, err := sender.Send(context.Background(), server, []byte("request")); !errors.Is(err, ErrMessageTruncated) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSyncSenderNoResponse(t *testing.T) {
+	server := newMockServer(t)
+	defer server.Stop()
+
+	sender := NewSyncSender(WithTimeout(5*time.Second))
+	_, err := sender.Send(context.Background(), server, []byte("request"))
+	if !errors.Is(err, ErrMessageTruncated) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if errors.Is(err, io.EOF) {
+		t.Fatalf("truncation should not be reported as EOF: %v", err)
+	}
+}
+
+func TestSyncSenderMessageTooLarge(t *testing.T) {
+	server := newMockServer(t, header(2048), bytes.Repeat([]byte("x"), 2048))
+	defer server.Stop()
+
+	sender := NewSyncSender(
+		WithMaxMessageSize(1024),
+		WithTimeout(5*time.Second))
+
+	if _, err := sender.Send(context.Background(), server, []byte("request")); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSyncSenderCancel(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen on loopback: %s", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		<-done
+		_ = conn.Close()
+	}()
+
+	server := &mockServer{
+		listener: listener,
+		port:     uint16(listener.Addr().(*net.TCPAddr).Port),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	sender := NewSyncSender(WithTimeout(30 * time.Second))
+	if _, err = sender.Send(ctx, server, []byte("request")); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("cancellation took %s", elapsed)
+	}
+}
@@ -0,0 +1,17 @@
+package devices
+
+import (
+    "context"
+)
+
+// Transport delivers a request to a device and returns the response. The
+// request and response are the plain JSON documents, any encoding required
+// on the wire is the responsibility of the Transport. Implementations must
+// give up and return ctx.Err() once the context is done.
+type Transport interface {
+    Send(ctx context.Context, d Addressable, data []byte) ([]byte, error)
+}
+
+var (
+    _ Transport = (*SyncSender)(nil)
+)
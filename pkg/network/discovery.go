@@ -0,0 +1,227 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/tplink"
+	"go.uber.org/zap"
+)
+
+var ErrNoDiscoveryTargets = errors.New("no address to send the discovery probe to")
+
+const maxDatagramSize = 65535
+
+// DiscoveredDevice is a device which answered a discovery probe. Config
+// addresses the device and Info holds the sysinfo it replied with.
+type DiscoveredDevice struct {
+	Config devices.DeviceConfig
+	Info   *tplink.SystemInfo
+}
+
+// Discover broadcasts a get_sysinfo probe to every configured target and
+// collects the replies until the discovery timeout expires. Replies are
+// de-duplicated by their source address.
+func (m *Manager) Discover() ([]DiscoveredDevice, error) {
+	return m.DiscoverContext(context.Background())
+}
+
+// DiscoverContext behaves like Discover but stops collecting replies once
+// the context is done. The devices found so far are returned together with
+// ctx.Err().
+func (m *Manager) DiscoverContext(ctx context.Context) ([]DiscoveredDevice, error) {
+	targets, err := m.targets()
+	if err != nil {
+		return nil, err
+	}
+
+	probe, err := json.Marshal(tplink.DeviceInfo{})
+	if err != nil {
+		return nil, err
+	}
+
+	payload, ok := tplink.EncryptDatagram(probe)
+	if !ok {
+		return nil, errors.New("failed to encrypt discovery probe")
+	}
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	sent := 0
+	for _, target := range targets {
+		if _, err = conn.WriteToUDP(payload.Bytes(), target); err != nil {
+			m.Logger().Warn("failed to send discovery probe",
+				zap.String("target", target.String()),
+				zap.Error(err))
+			continue
+		}
+		sent++
+	}
+
+	if sent == 0 {
+		return nil, err
+	}
+
+	if err = conn.SetReadDeadline(time.Now().Add(m.timeout)); err != nil {
+		return nil, err
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetReadDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	var found []DiscoveredDevice
+	seen := make(map[string]bool)
+	buffer := make([]byte, maxDatagramSize)
+
+	for {
+		n, addr, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return found, ctxErr
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			return found, err
+		}
+
+		if seen[addr.String()] {
+			continue
+		}
+
+		device, err := decodeReply(buffer[:n], addr)
+		if err != nil {
+			m.Logger().Debug("ignoring invalid discovery reply",
+				zap.String("address", addr.String()),
+				zap.Error(err))
+			continue
+		}
+
+		m.Logger().Debug("discovered device",
+			zap.String("address", addr.String()),
+			zap.String("alias", device.Info.Alias),
+			zap.String("model", device.Info.Model))
+
+		seen[addr.String()] = true
+		found = append(found, device)
+	}
+
+	return found, nil
+}
+
+func decodeReply(data []byte, addr *net.UDPAddr) (DiscoveredDevice, error) {
+	var result DiscoveredDevice
+
+	msg, ok := tplink.DecryptDatagram(data)
+	if !ok {
+		return result, errors.New("failed to decrypt reply")
+	}
+
+	var deviceInfo tplink.DeviceInfo
+	if err := json.Unmarshal(msg.Bytes(), &deviceInfo); err != nil {
+		return result, err
+	}
+
+	info := deviceInfo.SystemInfo()
+	if info.ErrorCode != 0 {
+		return result, fmt.Errorf("reply carried error code %d", info.ErrorCode)
+	}
+
+	result.Config = devices.NewDeviceConfig(addr.IP.String(),
+		devices.WithDeviceType(info.DeviceType()),
+		devices.WithPort(uint16(addr.Port)))
+	result.Info = info
+
+	return result, nil
+}
+
+// targets resolves the configured broadcast addresses and interfaces into
+// the list of addresses the probe is sent to. Without any configuration the
+// probe goes to the limited broadcast address.
+func (m *Manager) targets() ([]*net.UDPAddr, error) {
+	var targets []*net.UDPAddr
+
+	for _, address := range m.broadcast {
+		host, port := address, int(m.port)
+		if h, p, err := net.SplitHostPort(address); err == nil {
+			if port, err = strconv.Atoi(p); err != nil {
+				return nil, fmt.Errorf("invalid port in '%s': %w", address, err)
+			}
+			host = h
+		}
+
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid broadcast address '%s'", address)
+		}
+		targets = append(targets, &net.UDPAddr{IP: ip, Port: port})
+	}
+
+	for _, name := range m.interfaces {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if ip := broadcastAddress(ipNet); ip != nil {
+				targets = append(targets, &net.UDPAddr{IP: ip, Port: int(m.port)})
+			}
+		}
+	}
+
+	if len(m.broadcast) == 0 && len(m.interfaces) == 0 {
+		targets = append(targets, &net.UDPAddr{IP: net.IPv4bcast, Port: int(m.port)})
+	}
+
+	if len(targets) == 0 {
+		return nil, ErrNoDiscoveryTargets
+	}
+
+	return targets, nil
+}
+
+func broadcastAddress(ipNet *net.IPNet) net.IP {
+	ip := ipNet.IP.To4()
+	if ip == nil {
+		return nil
+	}
+
+	mask := ipNet.Mask
+	if len(mask) == net.IPv6len {
+		mask = mask[12:]
+	}
+
+	broadcast := make(net.IP, net.IPv4len)
+	for i := range ip {
+		broadcast[i] = ip[i] | ^mask[i]
+	}
+
+	return broadcast
+}
@@ -0,0 +1,170 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/tplink"
+)
+
+type mockResponder struct {
+	conn *net.UDPConn
+	info tplink.SystemInfo
+	test *testing.T
+}
+
+func newMockResponder(t *testing.T, info tplink.SystemInfo) *mockResponder {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen on loopback: %s", err)
+	}
+
+	r := &mockResponder{conn: conn, info: info, test: t}
+	go r.serve()
+
+	return r
+}
+
+func (r *mockResponder) Address() string {
+	return r.conn.LocalAddr().String()
+}
+
+func (r *mockResponder) Port() uint16 {
+	return uint16(r.conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func (r *mockResponder) serve() {
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+
+		msg, ok := tplink.DecryptDatagram(buf[:n])
+		if !ok {
+			r.test.Errorf("failed to decrypt discovery probe")
+			continue
+		}
+		if msg.String() != "{\"system\":{\"get_sysinfo\":{}}}" {
+			r.test.Errorf("unexpected discovery probe '%s'", msg.String())
+			continue
+		}
+
+		var reply tplink.DeviceInfo
+		reply.System.Info = r.info
+
+		data, err := json.Marshal(reply)
+		if err != nil {
+			r.test.Errorf("failed to marshal reply: %s", err)
+			continue
+		}
+
+		payload, _ := tplink.EncryptDatagram(data)
+		_, _ = r.conn.WriteToUDP(payload.Bytes(), addr)
+	}
+}
+
+func (r *mockResponder) Stop() {
+	_ = r.conn.Close()
+}
+
+func TestDiscover(t *testing.T) {
+	plug := newMockResponder(t, tplink.SystemInfo{
+		Alias: "Plug",
+		Model: "HS110(US)",
+		Type:  "IOT.SMARTPLUGSWITCH",
+	})
+	defer plug.Stop()
+
+	bulb := newMockResponder(t, tplink.SystemInfo{
+		Alias:   "Bulb",
+		MicType: "IOT.SMARTBULB",
+		Model:   "KL130(US)",
+	})
+	defer bulb.Stop()
+
+	mgr := NewManager(
+		WithBroadcastAddresses(plug.Address(), bulb.Address()),
+		WithDiscoveryTimeout(500*time.Millisecond))
+
+	found, err := mgr.Discover()
+	if err != nil {
+		t.Fatalf("failed to discover devices: %s", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("unexpected number of devices discovered: %d", len(found))
+	}
+
+	expected := map[uint16]struct {
+		alias string
+		kind  devices.DeviceType
+	}{
+		plug.Port(): {"Plug", devices.PlugDevice},
+		bulb.Port(): {"Bulb", devices.BulbDevice},
+	}
+
+	for _, device := range found {
+		want, ok := expected[device.Config.Port]
+		if !ok {
+			t.Fatalf("unexpected device at port %d", device.Config.Port)
+		}
+		if device.Config.Address != "127.0.0.1" {
+			t.Fatalf("unexpected device address '%s'", device.Config.Address)
+		}
+		if device.Info.Alias != want.alias {
+			t.Fatalf("unexpected alias '%s'", device.Info.Alias)
+		}
+		if device.Config.Type != want.kind {
+			t.Fatalf("unexpected device type '%s'", device.Config.Type)
+		}
+	}
+}
+
+func TestDiscoverNoReplies(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen on loopback: %s", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	mgr := NewManager(
+		WithBroadcastAddresses(conn.LocalAddr().String()),
+		WithDiscoveryTimeout(100*time.Millisecond))
+
+	found, err := mgr.Discover()
+	if err != nil {
+		t.Fatalf("failed to discover devices: %s", err)
+	}
+	if len(found) != 0 {
+		t.Fatalf("unexpected number of devices discovered: %d", len(found))
+	}
+}
+
+func TestDiscoverCancel(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("failed to listen on loopback: %s", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	mgr := NewManager(
+		WithBroadcastAddresses(conn.LocalAddr().String()),
+		WithDiscoveryTimeout(30*time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	if _, err = mgr.DiscoverContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("cancellation took %s", elapsed)
+	}
+}
@@ -1 +1,90 @@
 package network
+
+import (
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+	"go.uber.org/zap"
+)
+
+type ManagerOption func(*ManagerOptions)
+
+type ManagerOptions struct {
+	// BroadcastAddresses are sent the discovery probe in addition to the
+	// broadcast address of each interface. Entries are either 'host' or
+	// 'host:port', the port defaults to DiscoveryPort.
+	BroadcastAddresses []string
+	DiscoveryPort      uint16
+	DiscoveryTimeout   time.Duration
+	Interfaces         []string
+	Logger             *zap.Logger
+}
+
+func WithBroadcastAddresses(addresses ...string) ManagerOption {
+	return func(o *ManagerOptions) {
+		o.BroadcastAddresses = append(o.BroadcastAddresses, addresses...)
+	}
+}
+
+func WithDiscoveryPort(port uint16) ManagerOption {
+	return func(o *ManagerOptions) {
+		o.DiscoveryPort = port
+	}
+}
+
+func WithDiscoveryTimeout(timeout time.Duration) ManagerOption {
+	return func(o *ManagerOptions) {
+		o.DiscoveryTimeout = timeout
+	}
+}
+
+func WithInterfaces(names ...string) ManagerOption {
+	return func(o *ManagerOptions) {
+		o.Interfaces = append(o.Interfaces, names...)
+	}
+}
+
+func WithLogger(logger *zap.Logger) ManagerOption {
+	return func(o *ManagerOptions) {
+		o.Logger = logger
+	}
+}
+
+func DefaultManagerOptions() *ManagerOptions {
+	return &ManagerOptions{
+		DiscoveryPort:    devices.DefaultPort,
+		DiscoveryTimeout: 3 * time.Second,
+	}
+}
+
+type Manager struct {
+	broadcast  []string
+	interfaces []string
+	logger     *zap.Logger
+	port       uint16
+	timeout    time.Duration
+}
+
+func NewManager(opts ...ManagerOption) *Manager {
+	options := DefaultManagerOptions()
+	for _, option := range opts {
+		option(options)
+	}
+
+	if options.Logger == nil {
+		options.Logger = zap.NewNop()
+	}
+
+	mgr := new(Manager)
+	mgr.broadcast = options.BroadcastAddresses
+	mgr.interfaces = options.Interfaces
+	mgr.logger = options.Logger
+	mgr.port = options.DiscoveryPort
+	mgr.timeout = options.DiscoveryTimeout
+
+	return mgr
+}
+
+func (m *Manager) Logger() *zap.Logger {
+	return m.logger
+}
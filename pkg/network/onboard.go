@@ -0,0 +1,159 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/tplink"
+	"go.uber.org/zap"
+)
+
+var ErrNetworkNotFound = errors.New("network is not visible to the device")
+var ErrDeviceNotFound = errors.New("device did not appear on the network")
+
+// SetupAddress is the address of a factory-reset device on its own access
+// point.
+const SetupAddress = "192.168.0.1"
+
+// KeyTypeAuto takes the key type of the network from the scan of the device.
+const KeyTypeAuto = -1
+
+type OnboardOption func(*OnboardOptions)
+
+type OnboardOptions struct {
+	KeyType int
+	// WaitTimeout is how long to look for the device on the target network
+	// after it accepted the credentials. Zero does not wait.
+	WaitTimeout time.Duration
+}
+
+func WithKeyType(keyType int) OnboardOption {
+	return func(o *OnboardOptions) {
+		o.KeyType = keyType
+	}
+}
+
+func WithWaitTimeout(timeout time.Duration) OnboardOption {
+	return func(o *OnboardOptions) {
+		o.WaitTimeout = timeout
+	}
+}
+
+func DefaultOnboardOptions() *OnboardOptions {
+	return &OnboardOptions{
+		KeyType: KeyTypeAuto,
+	}
+}
+
+// OnboardResult describes an onboarded device. Info is the sysinfo read
+// before the device left its access point, Device is only set when waiting
+// for the device was requested.
+type OnboardResult struct {
+	Device  *DiscoveredDevice
+	Info    *tplink.SystemInfo
+	KeyType int
+}
+
+func (m *Manager) Onboard(api *tplink.DeviceManager, d *devices.Device, ssid string, password string, opts ...OnboardOption) (*OnboardResult, error) {
+	return m.OnboardContext(context.Background(), api, d, ssid, password, opts...)
+}
+
+// OnboardContext connects a device in access point mode to a wireless
+// network. The key type is taken from a scan of the device unless it is set
+// with WithKeyType.
+func (m *Manager) OnboardContext(ctx context.Context, api *tplink.DeviceManager, d *devices.Device, ssid string, password string, opts ...OnboardOption) (*OnboardResult, error) {
+	options := DefaultOnboardOptions()
+	for _, option := range opts {
+		option(options)
+	}
+
+	info, err := api.SystemInfoContext(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OnboardResult{Info: info, KeyType: options.KeyType}
+
+	if result.KeyType == KeyTypeAuto {
+		networks, err := api.ScanNetworksContext(ctx, d, true)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, network := range networks {
+			if network.SSID == ssid {
+				result.KeyType = network.KeyType
+				break
+			}
+		}
+
+		if result.KeyType == KeyTypeAuto {
+			return nil, fmt.Errorf("%w: %s", ErrNetworkNotFound, ssid)
+		}
+	}
+
+	m.Logger().Info("sending network credentials",
+		zap.String("device", info.DeviceId),
+		zap.String("ssid", ssid),
+		zap.Int("key_type", result.KeyType))
+
+	if err = api.JoinNetworkContext(ctx, d, ssid, password, result.KeyType); err != nil {
+		return nil, err
+	}
+
+	if options.WaitTimeout <= 0 {
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, options.WaitTimeout)
+	defer cancel()
+
+	if result.Device, err = m.WaitForDeviceContext(ctx, info); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+func (m *Manager) WaitForDevice(info *tplink.SystemInfo) (*DiscoveredDevice, error) {
+	return m.WaitForDeviceContext(context.Background(), info)
+}
+
+// WaitForDeviceContext repeats discovery until the device described by info
+// answers, matched by its device id or else its MAC address. Discovery
+// errors are expected while the host itself changes networks and are only
+// logged.
+func (m *Manager) WaitForDeviceContext(ctx context.Context, info *tplink.SystemInfo) (*DiscoveredDevice, error) {
+	for {
+		found, err := m.DiscoverContext(ctx)
+		for i := range found {
+			if sameDevice(info, found[i].Info) {
+				return &found[i], nil
+			}
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("%w: %w", ErrDeviceNotFound, ctxErr)
+		}
+		if err != nil {
+			m.Logger().Debug("discovery failed while waiting for device",
+				zap.Error(err))
+
+			timer := time.NewTimer(time.Second)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+func sameDevice(a *tplink.SystemInfo, b *tplink.SystemInfo) bool {
+	if a.DeviceId != "" {
+		return a.DeviceId == b.DeviceId
+	}
+	return a.MacAddress != "" && a.MacAddress == b.MacAddress
+}
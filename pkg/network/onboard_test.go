@@ -0,0 +1,134 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/tplink"
+)
+
+const setupDeviceId = "8006000000000000000000000000000000000042"
+
+// setupTransport emulates a factory-reset plug on its access point.
+type setupTransport struct {
+	joined map[string]interface{}
+}
+
+func (s *setupTransport) Send(ctx context.Context, d devices.Addressable, data []byte) ([]byte, error) {
+	var req map[string]map[string]map[string]interface{}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	res := map[string]interface{}{}
+	switch {
+	case req["system"]["get_sysinfo"] != nil:
+		res["system"] = map[string]interface{}{"get_sysinfo": map[string]interface{}{
+			"err_code": 0,
+			"deviceId": setupDeviceId,
+			"model":    "HS110(US)",
+		}}
+	case req["netif"]["get_scaninfo"] != nil:
+		res["netif"] = map[string]interface{}{"get_scaninfo": map[string]interface{}{
+			"err_code": 0,
+			"ap_list": []map[string]interface{}{
+				{"ssid": "Cafe", "key_type": tplink.KeyTypeNone},
+				{"ssid": "Home", "key_type": tplink.KeyTypeWPA},
+			},
+		}}
+	case req["netif"]["set_stainfo"] != nil:
+		s.joined = req["netif"]["set_stainfo"]
+		res["netif"] = map[string]interface{}{"set_stainfo": map[string]interface{}{"err_code": 0}}
+	}
+
+	return json.Marshal(res)
+}
+
+func newSetupManager() (*tplink.DeviceManager, *setupTransport, *devices.Device) {
+	transport := &setupTransport{}
+	api := tplink.NewDeviceManager(devices.NewDeviceManager(), tplink.WithTransport(transport))
+
+	config := devices.NewDeviceConfig(SetupAddress)
+	return api, transport, devices.NewDevice(&config)
+}
+
+func TestOnboard(t *testing.T) {
+	plug := newMockResponder(t, tplink.SystemInfo{
+		Alias:    "Plug",
+		DeviceId: setupDeviceId,
+		Type:     "IOT.SMARTPLUGSWITCH",
+	})
+	defer plug.Stop()
+
+	other := newMockResponder(t, tplink.SystemInfo{
+		Alias:    "Other",
+		DeviceId: "8006000000000000000000000000000000000001",
+		Type:     "IOT.SMARTPLUGSWITCH",
+	})
+	defer other.Stop()
+
+	mgr := NewManager(
+		WithBroadcastAddresses(other.Address(), plug.Address()),
+		WithDiscoveryTimeout(100*time.Millisecond))
+
+	api, transport, device := newSetupManager()
+
+	result, err := mgr.Onboard(api, device, "Home", "secret", WithWaitTimeout(5*time.Second))
+	if err != nil {
+		t.Fatalf("failed to onboard device: %s", err)
+	}
+
+	if result.KeyType != tplink.KeyTypeWPA {
+		t.Fatalf("unexpected key type %d", result.KeyType)
+	}
+	if transport.joined["ssid"] != "Home" || transport.joined["password"] != "secret" || transport.joined["key_type"] != float64(tplink.KeyTypeWPA) {
+		t.Fatalf("unexpected credentials %v", transport.joined)
+	}
+	if result.Device == nil || result.Device.Config.Port != plug.Port() {
+		t.Fatalf("unexpected device %+v", result.Device)
+	}
+}
+
+func TestOnboardErrors(t *testing.T) {
+	mgr := NewManager(WithBroadcastAddresses("127.0.0.1:9"))
+	api, transport, device := newSetupManager()
+
+	if _, err := mgr.Onboard(api, device, "Missing", "secret"); !errors.Is(err, ErrNetworkNotFound) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := mgr.Onboard(api, device, "Home", ""); !errors.Is(err, tplink.ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transport.joined != nil {
+		t.Fatalf("credentials were sent to the device")
+	}
+
+	// An open network needs no password and the key type can be forced.
+	if _, err := mgr.Onboard(api, device, "Hidden", "", WithKeyType(tplink.KeyTypeNone)); err != nil {
+		t.Fatalf("failed to onboard device: %s", err)
+	}
+	if transport.joined["ssid"] != "Hidden" || transport.joined["key_type"] != float64(tplink.KeyTypeNone) {
+		t.Fatalf("unexpected credentials %v", transport.joined)
+	}
+}
+
+func TestWaitForDeviceTimeout(t *testing.T) {
+	plug := newMockResponder(t, tplink.SystemInfo{DeviceId: "8006000000000000000000000000000000000001"})
+	defer plug.Stop()
+
+	mgr := NewManager(
+		WithBroadcastAddresses(plug.Address()),
+		WithDiscoveryTimeout(50*time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err := mgr.WaitForDeviceContext(ctx, &tplink.SystemInfo{DeviceId: setupDeviceId})
+	if !errors.Is(err, ErrDeviceNotFound) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
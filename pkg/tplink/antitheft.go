@@ -0,0 +1,109 @@
+package tplink
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+func (m *DeviceManager) AddAntiTheftRule(d *devices.Device, rule AntiTheftRule) (string, error) {
+	return m.AddAntiTheftRuleContext(context.Background(), d, rule)
+}
+
+// AddAntiTheftRuleContext stores the away mode rule on the device and
+// returns its id.
+func (m *DeviceManager) AddAntiTheftRuleContext(ctx context.Context, d *devices.Device, rule AntiTheftRule) (string, error) {
+	if err := validateAntiTheft(&rule); err != nil {
+		return "", err
+	}
+
+	var r AntiTheftAddRule
+	r.SetRule(rule)
+
+	if err := m.DoContext(ctx, d, &r); err != nil {
+		return "", err
+	}
+
+	return r.GetId(), nil
+}
+
+func (m *DeviceManager) AntiTheftRules(d *devices.Device) (*AntiTheftRules, error) {
+	return m.AntiTheftRulesContext(context.Background(), d)
+}
+
+func (m *DeviceManager) AntiTheftRulesContext(ctx context.Context, d *devices.Device) (*AntiTheftRules, error) {
+	var r AntiTheftRules
+
+	if err := m.DoContext(ctx, d, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+func (m *DeviceManager) DeleteAntiTheftRule(d *devices.Device, id string) error {
+	return m.DeleteAntiTheftRuleContext(context.Background(), d, id)
+}
+
+func (m *DeviceManager) DeleteAntiTheftRuleContext(ctx context.Context, d *devices.Device, id string) error {
+	var r AntiTheftDeleteRule
+	r.SetId(id)
+
+	return m.DoContext(ctx, d, &r)
+}
+
+func (m *DeviceManager) DeleteAntiTheftRules(d *devices.Device) error {
+	return m.DeleteAntiTheftRulesContext(context.Background(), d)
+}
+
+func (m *DeviceManager) DeleteAntiTheftRulesContext(ctx context.Context, d *devices.Device) error {
+	var r AntiTheftDeleteRules
+
+	return m.DoContext(ctx, d, &r)
+}
+
+func (m *DeviceManager) EditAntiTheftRule(d *devices.Device, rule AntiTheftRule) error {
+	return m.EditAntiTheftRuleContext(context.Background(), d, rule)
+}
+
+func (m *DeviceManager) EditAntiTheftRuleContext(ctx context.Context, d *devices.Device, rule AntiTheftRule) error {
+	if rule.Id == "" {
+		return fmt.Errorf("%w: rule has no id", ErrInvalidParameter)
+	}
+	if err := validateAntiTheft(&rule); err != nil {
+		return err
+	}
+
+	var r AntiTheftEditRule
+	r.SetRule(rule)
+
+	return m.DoContext(ctx, d, &r)
+}
+
+func (m *DeviceManager) SetAntiTheftEnabled(d *devices.Device, enabled bool) error {
+	return m.SetAntiTheftEnabledContext(context.Background(), d, enabled)
+}
+
+func (m *DeviceManager) SetAntiTheftEnabledContext(ctx context.Context, d *devices.Device, enabled bool) error {
+	var r AntiTheftEnable
+	r.SetEnabled(enabled)
+
+	return m.DoContext(ctx, d, &r)
+}
+
+func validateAntiTheft(rule *AntiTheftRule) error {
+	if rule.Repeat != 0 && rule.Days == 0 {
+		return fmt.Errorf("%w: repeating rule has no weekdays", ErrInvalidParameter)
+	}
+	if err := validateScheduleTime("start", rule.StartOpt, rule.StartMin, 0); err != nil {
+		return err
+	}
+	if err := validateScheduleTime("end", rule.EndOpt, rule.EndMin, 0); err != nil {
+		return err
+	}
+	if rule.StartOpt == ScheduleTimeNone || rule.EndOpt == ScheduleTimeNone {
+		return fmt.Errorf("%w: away mode needs a start and an end time", ErrInvalidParameter)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package tplink
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+func antiTheftModules() mockModules {
+	var rules []map[string]interface{}
+	enable := 0
+
+	modules := plugModules()
+	modules["anti_theft"] = map[string]mockMethod{
+		"get_rules": func(map[string]interface{}) interface{} {
+			return map[string]interface{}{"err_code": 0, "enable": enable, "rule_list": rules, "version": 2}
+		},
+		"add_rule": func(params map[string]interface{}) interface{} {
+			params["id"] = "AWAY01"
+			rules = append(rules, params)
+			return map[string]interface{}{"err_code": 0, "id": params["id"]}
+		},
+		"delete_rule": func(params map[string]interface{}) interface{} {
+			for i, rule := range rules {
+				if rule["id"] == params["id"] {
+					rules = append(rules[:i], rules[i+1:]...)
+					return map[string]interface{}{"err_code": 0}
+				}
+			}
+			return map[string]interface{}{"err_code": -14, "err_msg": "entry not exist"}
+		},
+		"set_overall_enable": func(params map[string]interface{}) interface{} {
+			v, _ := params["enable"].(float64)
+			enable = int(v)
+			return map[string]interface{}{"err_code": 0}
+		},
+	}
+	return modules
+}
+
+func TestAntiTheft(t *testing.T) {
+	api, _ := newMockManager(antiTheftModules().handler)
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	rule := AntiTheftRule{
+		Name:      "away",
+		Enable:    1,
+		Days:      Weekend,
+		StartOpt:  ScheduleTimeMinutes,
+		StartMin:  18 * 60,
+		EndOpt:    ScheduleTimeMinutes,
+		EndMin:    23 * 60,
+		Repeat:    1,
+		Frequency: 5,
+	}
+
+	id, err := api.AddAntiTheftRule(device, rule)
+	if err != nil {
+		t.Fatalf("failed to add away rule: %s", err)
+	}
+	if err = api.SetAntiTheftEnabled(device, true); err != nil {
+		t.Fatalf("failed to enable away mode: %s", err)
+	}
+
+	rules, err := api.AntiTheftRules(device)
+	if err != nil {
+		t.Fatalf("failed to get away rules: %s", err)
+	}
+	if !rules.GetEnabled() || len(rules.GetRules()) != 1 {
+		t.Fatalf("unexpected away rules %+v", rules)
+	}
+	if r := rules.GetRules()[0]; r.Id != id || r.Days != Weekend || r.EndMin != 1380 || r.Frequency != 5 {
+		t.Fatalf("unexpected away rule %+v", r)
+	}
+
+	if err = api.DeleteAntiTheftRule(device, id); err != nil {
+		t.Fatalf("failed to delete away rule: %s", err)
+	}
+	if err = api.DeleteAntiTheftRule(device, id); !errors.Is(err, ErrProtocolOperationFailed) || errors.Is(err, ErrUnsupportedFeature) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rule.EndOpt = ScheduleTimeNone
+	if _, err = api.AddAntiTheftRule(device, rule); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// delete_all_rules is not emulated.
+	if err = api.DeleteAntiTheftRules(device); !errors.Is(err, ErrUnsupportedFeature) || !IsMethodNotSupported(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
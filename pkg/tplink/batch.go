@@ -0,0 +1,127 @@
+package tplink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+var ErrEmptyBatch = errors.New("batch does not contain any calls")
+
+// Batch composes calls to several modules into a single request so they are
+// answered in one round trip, for example:
+//
+//	{"system":{"get_sysinfo":{}},"emeter":{"get_realtime":{}}}
+type Batch struct {
+	calls map[string]map[string]interface{}
+	count int
+}
+
+func NewBatch() *Batch {
+	return &Batch{calls: make(map[string]map[string]interface{})}
+}
+
+// Add queues module.method with the given parameters. A nil params sends an
+// empty object. Adding the same method twice replaces the parameters.
+func (b *Batch) Add(module string, method string, params interface{}) *Batch {
+	if params == nil {
+		params = struct{}{}
+	}
+
+	methods, ok := b.calls[module]
+	if !ok {
+		methods = make(map[string]interface{})
+		b.calls[module] = methods
+	}
+
+	if _, ok = methods[method]; !ok {
+		b.count++
+	}
+	methods[method] = params
+
+	return b
+}
+
+func (b *Batch) Len() int {
+	return b.count
+}
+
+func (b *Batch) MarshalJSON() ([]byte, error) {
+	return json.Marshal(b.calls)
+}
+
+// BatchResponse holds the response to a Batch. Every call carries its own
+// error code, so each result is checked independently when it is decoded.
+type BatchResponse struct {
+	device  devices.Addressable
+	modules map[string]json.RawMessage
+}
+
+// Err returns the ProtocolError reported for module.method, if any.
+func (r *BatchResponse) Err(module string, method string) error {
+	_, err := r.Raw(module, method)
+	return err
+}
+
+// Decode unmarshals the result of module.method into out.
+func (r *BatchResponse) Decode(module string, method string, out interface{}) error {
+	raw, err := r.Raw(module, method)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// Raw returns the undecoded result of module.method.
+func (r *BatchResponse) Raw(module string, method string) (json.RawMessage, error) {
+	raw, ok := r.modules[module]
+	if !ok {
+		return nil, protocolError(r.device, module, method, errorCode{
+			ErrorCode:    ErrCodeModuleNotSupported,
+			ErrorMessage: "module missing from response",
+		})
+	}
+	return decodeModule(r.device, raw, module, method)
+}
+
+func (m *DeviceManager) SendBatch(d devices.Addressable, b *Batch) (*BatchResponse, error) {
+	return m.SendBatchContext(context.Background(), d, b)
+}
+
+// SendBatchContext sends all calls in the batch to the device in a single
+// request. Only transport failures are returned as an error, protocol
+// errors are reported per call by the BatchResponse.
+func (m *DeviceManager) SendBatchContext(ctx context.Context, d devices.Addressable, b *Batch) (*BatchResponse, error) {
+	if b.Len() == 0 {
+		return nil, ErrEmptyBatch
+	}
+
+	res, err := m.MarshalContext(ctx, d, b)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &BatchResponse{device: d}
+	if err = json.Unmarshal(res, &response.modules); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// call sends a single module.method request and decodes the result into out,
+// for modules whose name differs between device types. A nil out only checks
+// the error code of the result.
+func (m *DeviceManager) call(ctx context.Context, d devices.Addressable, module string, method string, params interface{}, out interface{}) error {
+	res, err := m.SendBatchContext(ctx, d, NewBatch().Add(module, method, params))
+	if err != nil {
+		return err
+	}
+
+	if out == nil {
+		return res.Err(module, method)
+	}
+	return res.Decode(module, method, out)
+}
@@ -0,0 +1,65 @@
+package tplink
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+func TestBatch(t *testing.T) {
+	api, transport := newMockManager(plugHandler)
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	batch := NewBatch().
+		Add("system", "get_sysinfo", nil).
+		Add("emeter", "get_realtime", nil).
+		Add("time", "get_time", nil)
+
+	if batch.Len() != 3 {
+		t.Fatalf("unexpected batch length %d", batch.Len())
+	}
+
+	res, err := api.SendBatch(device, batch)
+	if err != nil {
+		t.Fatalf("failed to send batch: %s", err)
+	}
+
+	if requests := transport.Requests(); len(requests) != 1 {
+		t.Fatalf("unexpected number of requests: %d", len(requests))
+	}
+
+	var info SystemInfo
+	if err = res.Decode("system", "get_sysinfo", &info); err != nil {
+		t.Fatalf("failed to decode system info: %s", err)
+	}
+	if info.Alias != "Mock Plug" {
+		t.Fatalf("unexpected alias '%s'", info.Alias)
+	}
+
+	var energy RealTimeEnergy
+	if err = res.Decode("emeter", "get_realtime", &energy); err != nil {
+		t.Fatalf("failed to decode realtime energy: %s", err)
+	}
+	if !floatCompare(float64(energy.Voltage), 120.0) {
+		t.Fatalf("unexpected voltage %f", energy.Voltage)
+	}
+
+	if err = res.Err("time", "get_time"); !IsModuleNotSupported(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = res.Err("system", "get_time"); !IsMethodNotSupported(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestBatchEmpty(t *testing.T) {
+	api, _ := newMockManager(plugHandler)
+
+	config := PlugConfig("mock")
+	if _, err := api.SendBatch(devices.NewDevice(&config), NewBatch()); !errors.Is(err, ErrEmptyBatch) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
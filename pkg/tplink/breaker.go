@@ -0,0 +1,194 @@
+package tplink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+	"go.uber.org/zap"
+)
+
+var ErrCircuitOpen = errors.New("circuit breaker is open for the device")
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+var breakerStateMap = map[BreakerState]string{
+	BreakerClosed:   "Closed",
+	BreakerOpen:     "Open",
+	BreakerHalfOpen: "HalfOpen",
+}
+
+func (s BreakerState) String() string {
+	if v, ok := breakerStateMap[s]; ok {
+		return v
+	}
+	return "Unknown"
+}
+
+// BreakerPolicy configures the per-device circuit breaker. After
+// FailureThreshold consecutive failed requests the breaker opens and
+// requests fail with ErrCircuitOpen without touching the network. Once
+// CoolDown has passed a single probe request is let through, closing the
+// breaker on success and opening it again on failure.
+type BreakerPolicy struct {
+	// FailureThreshold of zero disables the circuit breaker.
+	FailureThreshold int
+	CoolDown         time.Duration
+
+	// OnStateChange is called after every transition of a breaker. It must
+	// not block.
+	OnStateChange func(address string, from BreakerState, to BreakerState)
+}
+
+func DefaultBreakerPolicy() BreakerPolicy {
+	return BreakerPolicy{
+		FailureThreshold: 3,
+		CoolDown:         time.Minute,
+	}
+}
+
+type circuitBreaker struct {
+	address  string
+	failures int
+	logger   *zap.Logger
+	mutex    sync.Mutex
+	openedAt time.Time
+	policy   BreakerPolicy
+	probing  bool
+	state    BreakerState
+}
+
+func (m *DeviceManager) circuitBreaker(d devices.Addressable) *circuitBreaker {
+	if m.breakerPolicy.FailureThreshold <= 0 {
+		return nil
+	}
+
+	address := deviceAddress(d)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	breaker, ok := m.breakers[address]
+	if !ok {
+		breaker = &circuitBreaker{
+			address: address,
+			logger:  m.Logger(),
+			policy:  m.breakerPolicy,
+			state:   BreakerClosed,
+		}
+		m.breakers[address] = breaker
+	}
+
+	return breaker
+}
+
+// BreakerState returns the state of the device's circuit breaker. Devices
+// are reported as BreakerClosed while the breaker is disabled.
+func (m *DeviceManager) BreakerState(d devices.Addressable) BreakerState {
+	breaker := m.circuitBreaker(d)
+	if breaker == nil {
+		return BreakerClosed
+	}
+
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	return breaker.state
+}
+
+// allow returns ErrCircuitOpen while the breaker is open or a probe request
+// is already in flight. probe is true when the caller is the single request
+// let through to test a half-open breaker.
+func (b *circuitBreaker) allow() (probe bool, err error) {
+	if b == nil {
+		return false, nil
+	}
+
+	b.mutex.Lock()
+	from := b.state
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.policy.CoolDown {
+			b.mutex.Unlock()
+			return false, ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		probe = true
+	case BreakerHalfOpen:
+		if b.probing {
+			b.mutex.Unlock()
+			return false, ErrCircuitOpen
+		}
+		b.probing = true
+		probe = true
+	}
+
+	to := b.state
+	b.mutex.Unlock()
+
+	b.transition(from, to)
+	return probe, nil
+}
+
+// record updates the breaker with the outcome of a request. Requests which
+// were cancelled by the caller say nothing about the device and are ignored.
+// Only the probe decides the state of a half-open breaker, requests admitted
+// before the breaker opened do not change it once they finish.
+func (b *circuitBreaker) record(ctx context.Context, probe bool, err error) {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	from := b.state
+
+	if probe {
+		b.probing = false
+	} else if b.state != BreakerClosed {
+		b.mutex.Unlock()
+		return
+	}
+
+	switch {
+	case err == nil:
+		b.failures = 0
+		b.state = BreakerClosed
+	case ctx.Err() != nil:
+	default:
+		b.failures++
+		if probe || b.failures >= b.policy.FailureThreshold {
+			b.openedAt = time.Now()
+			b.state = BreakerOpen
+		}
+	}
+
+	to := b.state
+	b.mutex.Unlock()
+
+	b.transition(from, to)
+}
+
+func (b *circuitBreaker) transition(from BreakerState, to BreakerState) {
+	if from == to {
+		return
+	}
+
+	b.logger.Info("circuit breaker state changed",
+		zap.String("address", b.address),
+		zap.Stringer("from", from),
+		zap.Stringer("to", to))
+
+	if b.policy.OnStateChange != nil {
+		b.policy.OnStateChange(b.address, from, to)
+	}
+}
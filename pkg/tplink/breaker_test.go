@@ -0,0 +1,170 @@
+package tplink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+	"go.uber.org/zap"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	transport := &flakyTransport{
+		err:       os.ErrDeadlineExceeded,
+		failures:  3,
+		transport: newMockTransport(plugHandler),
+	}
+
+	var mutex sync.Mutex
+	var transitions []string
+
+	policy := BreakerPolicy{
+		FailureThreshold: 2,
+		CoolDown:         50 * time.Millisecond,
+		OnStateChange: func(address string, from BreakerState, to BreakerState) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			transitions = append(transitions, fmt.Sprintf("%s:%s", from, to))
+		},
+	}
+
+	api := NewDeviceManager(devices.NewDeviceManager(),
+		WithTransport(transport),
+		WithRetryPolicy(NoRetryPolicy()),
+		WithCircuitBreaker(policy))
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	for i := 0; i < 2; i++ {
+		if _, err := api.SystemInfo(device); !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if api.BreakerState(device) != BreakerOpen {
+		t.Fatalf("unexpected breaker state %s", api.BreakerState(device))
+	}
+
+	// The open breaker fails fast without touching the transport.
+	if _, err := api.SystemInfo(device); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transport.attempts != 2 {
+		t.Fatalf("unexpected number of attempts: %d", transport.attempts)
+	}
+
+	// The probe after the cool-down fails and opens the breaker again.
+	time.Sleep(60 * time.Millisecond)
+	if _, err := api.SystemInfo(device); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if api.BreakerState(device) != BreakerOpen {
+		t.Fatalf("unexpected breaker state %s", api.BreakerState(device))
+	}
+
+	// The next probe succeeds and closes the breaker.
+	time.Sleep(60 * time.Millisecond)
+	if _, err := api.SystemInfo(device); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if api.BreakerState(device) != BreakerClosed {
+		t.Fatalf("unexpected breaker state %s", api.BreakerState(device))
+	}
+
+	expected := []string{
+		"Closed:Open",
+		"Open:HalfOpen",
+		"HalfOpen:Open",
+		"Open:HalfOpen",
+		"HalfOpen:Closed",
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if fmt.Sprint(transitions) != fmt.Sprint(expected) {
+		t.Fatalf("unexpected transitions %v", transitions)
+	}
+}
+
+func TestCircuitBreakerIgnoresProtocolErrors(t *testing.T) {
+	modules := plugModules()
+	delete(modules["system"], "reboot")
+
+	api, _ := newMockManager(modules.handler,
+		WithCircuitBreaker(BreakerPolicy{FailureThreshold: 1, CoolDown: time.Minute}))
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	for i := 0; i < 3; i++ {
+		if err := api.Reboot(device, 1); !IsMethodNotSupported(err) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if api.BreakerState(device) != BreakerClosed {
+		t.Fatalf("unexpected breaker state %s", api.BreakerState(device))
+	}
+}
+
+func TestCircuitBreakerSingleProbe(t *testing.T) {
+	var transitions []string
+	breaker := &circuitBreaker{
+		logger: zap.NewNop(),
+		policy: BreakerPolicy{
+			FailureThreshold: 1,
+			CoolDown:         10 * time.Millisecond,
+			OnStateChange: func(address string, from BreakerState, to BreakerState) {
+				transitions = append(transitions, fmt.Sprintf("%s:%s", from, to))
+			},
+		},
+		state: BreakerClosed,
+	}
+	ctx := context.Background()
+
+	// A request admitted while the breaker is closed is still in flight, for
+	// example backing off between retries, when another one fails.
+	slow, err := breaker.allow()
+	if err != nil || slow {
+		t.Fatalf("unexpected admission %v: %v", slow, err)
+	}
+	if _, err = breaker.allow(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	breaker.record(ctx, false, os.ErrDeadlineExceeded)
+
+	time.Sleep(20 * time.Millisecond)
+	probe, err := breaker.allow()
+	if err != nil || !probe {
+		t.Fatalf("unexpected admission %v: %v", probe, err)
+	}
+
+	// The slow request succeeds, which neither closes the breaker on behalf
+	// of the probe nor lets a second probe through.
+	breaker.record(ctx, slow, nil)
+	if breaker.state != BreakerHalfOpen {
+		t.Fatalf("unexpected breaker state %s", breaker.state)
+	}
+	if _, err = breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	breaker.record(ctx, probe, os.ErrDeadlineExceeded)
+	if breaker.state != BreakerOpen {
+		t.Fatalf("unexpected breaker state %s", breaker.state)
+	}
+
+	expected := []string{
+		"Closed:Open",
+		"Open:HalfOpen",
+		"HalfOpen:Open",
+	}
+	if fmt.Sprint(transitions) != fmt.Sprint(expected) {
+		t.Fatalf("unexpected transitions %v", transitions)
+	}
+}
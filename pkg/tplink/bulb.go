@@ -0,0 +1,250 @@
+package tplink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+var ErrInvalidParameter = errors.New("parameter is out of range")
+
+// colorTempRanges lists the supported color temperatures in Kelvin of bulbs
+// with a variable color temperature, keyed by model prefix. Models whose
+// range depends on the region list every region.
+var colorTempRanges = map[string][2]int{
+	"KB130":     {2500, 9000},
+	"KL120(EU)": {2700, 6500},
+	"KL120(US)": {2700, 5000},
+	"KL125":     {2500, 6500},
+	"KL130":     {2500, 9000},
+	"KL135":     {2500, 6500},
+	"KL430":     {2500, 9000},
+	"LB120":     {2700, 6500},
+	"LB130":     {2500, 9000},
+	"LB230":     {2500, 9000},
+}
+
+var defaultColorTempRange = [2]int{2500, 9000}
+
+// Bulb controls the lighting service of a smart bulb. The capabilities are
+// taken from the sysinfo flags at the time the Bulb was created and every
+// operation the bulb does not support is rejected before it is sent.
+type Bulb struct {
+	device devices.Addressable
+	info   *SystemInfo
+	mgr    *DeviceManager
+}
+
+func (m *DeviceManager) Bulb(d *devices.Device) (*Bulb, error) {
+	return m.BulbContext(context.Background(), d)
+}
+
+func (m *DeviceManager) BulbContext(ctx context.Context, d *devices.Device) (*Bulb, error) {
+	info, err := m.SystemInfoContext(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.DeviceType() != devices.BulbDevice && info.LightState == nil {
+		return nil, ErrUnsupportedFeature
+	}
+
+	return &Bulb{device: d, info: info, mgr: m}, nil
+}
+
+func (b *Bulb) ColorTempRange() (int, int) {
+	r := colorTempRange(b.info.Model)
+	return r[0], r[1]
+}
+
+// colorTempRange looks up the range of a model by the longest matching
+// prefix. A known model with a region which is not listed gets the narrowest
+// range of its regions, the default range is only used for unknown models.
+func colorTempRange(model string) [2]int {
+	var match string
+	for prefix := range colorTempRanges {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if match != "" {
+		return colorTempRanges[match]
+	}
+
+	base, _, _ := strings.Cut(model, "(")
+
+	var narrowest [2]int
+	var found bool
+	for prefix, r := range colorTempRanges {
+		if p, _, _ := strings.Cut(prefix, "("); p != base {
+			continue
+		}
+		if !found || r[1]-r[0] < narrowest[1]-narrowest[0] ||
+			(r[1]-r[0] == narrowest[1]-narrowest[0] && r[0] > narrowest[0]) {
+			narrowest = r
+			found = true
+		}
+	}
+	if found {
+		return narrowest
+	}
+
+	return defaultColorTempRange
+}
+
+func (b *Bulb) IsColor() bool {
+	return b.info.IsColor != 0
+}
+
+func (b *Bulb) IsDimmable() bool {
+	return b.info.IsDimmable != 0
+}
+
+func (b *Bulb) IsVariableColorTemp() bool {
+	return b.info.IsVariableColorTemp != 0
+}
+
+func (b *Bulb) LightState() (*LightState, error) {
+	return b.LightStateContext(context.Background())
+}
+
+func (b *Bulb) LightStateContext(ctx context.Context) (*LightState, error) {
+	var state BulbLightState
+
+	if err := b.mgr.DoContext(ctx, b.device, &state); err != nil {
+		return nil, err
+	}
+
+	return state.LightState(), nil
+}
+
+func (b *Bulb) SetBrightness(brightness int, period time.Duration) (*LightState, error) {
+	return b.SetBrightnessContext(context.Background(), brightness, period)
+}
+
+func (b *Bulb) SetBrightnessContext(ctx context.Context, brightness int, period time.Duration) (*LightState, error) {
+	return b.TransitionContext(ctx, LightTransition{
+		Brightness:       &brightness,
+		TransitionPeriod: transitionPeriod(period),
+		IgnoreDefault:    1,
+	})
+}
+
+func (b *Bulb) SetColorTemp(kelvin int, period time.Duration) (*LightState, error) {
+	return b.SetColorTempContext(context.Background(), kelvin, period)
+}
+
+func (b *Bulb) SetColorTempContext(ctx context.Context, kelvin int, period time.Duration) (*LightState, error) {
+	return b.TransitionContext(ctx, LightTransition{
+		ColorTemp:        &kelvin,
+		TransitionPeriod: transitionPeriod(period),
+		IgnoreDefault:    1,
+	})
+}
+
+// SetHSV sets the color of the bulb. Hue is in degrees, saturation and value
+// (brightness) are percentages.
+func (b *Bulb) SetHSV(hue int, saturation int, value int, period time.Duration) (*LightState, error) {
+	return b.SetHSVContext(context.Background(), hue, saturation, value, period)
+}
+
+func (b *Bulb) SetHSVContext(ctx context.Context, hue int, saturation int, value int, period time.Duration) (*LightState, error) {
+	colorTemp := 0
+
+	return b.TransitionContext(ctx, LightTransition{
+		Hue:              &hue,
+		Saturation:       &saturation,
+		Brightness:       &value,
+		ColorTemp:        &colorTemp,
+		TransitionPeriod: transitionPeriod(period),
+		IgnoreDefault:    1,
+	})
+}
+
+func (b *Bulb) SetOn(on bool, period time.Duration) (*LightState, error) {
+	return b.SetOnContext(context.Background(), on, period)
+}
+
+func (b *Bulb) SetOnContext(ctx context.Context, on bool, period time.Duration) (*LightState, error) {
+	state := 0
+	if on {
+		state = 1
+	}
+
+	return b.TransitionContext(ctx, LightTransition{
+		OnOff:            &state,
+		TransitionPeriod: transitionPeriod(period),
+	})
+}
+
+func (b *Bulb) Transition(t LightTransition) (*LightState, error) {
+	return b.TransitionContext(context.Background(), t)
+}
+
+// TransitionContext validates the transition against the capabilities of
+// the bulb and sends it. The light state after the transition is returned.
+func (b *Bulb) TransitionContext(ctx context.Context, t LightTransition) (*LightState, error) {
+	if err := b.validate(&t); err != nil {
+		return nil, err
+	}
+
+	var req BulbTransition
+	req.SetTransition(t)
+
+	res, err := ExecuteContext[*BulbTransition, bulbTransitionResult](ctx, b.mgr, b.device, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &res.Service.State, nil
+}
+
+func (b *Bulb) validate(t *LightTransition) error {
+	if t.TransitionPeriod < 0 {
+		return fmt.Errorf("%w: transition period %dms", ErrInvalidParameter, t.TransitionPeriod)
+	}
+
+	if t.Brightness != nil {
+		if !b.IsDimmable() {
+			return fmt.Errorf("%w: bulb is not dimmable", ErrUnsupportedFeature)
+		}
+		if *t.Brightness < 0 || *t.Brightness > 100 {
+			return fmt.Errorf("%w: brightness %d", ErrInvalidParameter, *t.Brightness)
+		}
+	}
+
+	if t.Hue != nil || t.Saturation != nil {
+		if !b.IsColor() {
+			return fmt.Errorf("%w: bulb does not support colors", ErrUnsupportedFeature)
+		}
+		if t.Hue != nil && (*t.Hue < 0 || *t.Hue > 360) {
+			return fmt.Errorf("%w: hue %d", ErrInvalidParameter, *t.Hue)
+		}
+		if t.Saturation != nil && (*t.Saturation < 0 || *t.Saturation > 100) {
+			return fmt.Errorf("%w: saturation %d", ErrInvalidParameter, *t.Saturation)
+		}
+	}
+
+	// A color temperature of zero switches a color bulb back to its color.
+	if t.ColorTemp != nil && (*t.ColorTemp != 0 || !b.IsColor()) {
+		if !b.IsVariableColorTemp() {
+			return fmt.Errorf("%w: bulb does not support color temperatures", ErrUnsupportedFeature)
+		}
+
+		low, high := b.ColorTempRange()
+		if *t.ColorTemp < low || *t.ColorTemp > high {
+			return fmt.Errorf("%w: color temperature %dK outside of %dK-%dK",
+				ErrInvalidParameter, *t.ColorTemp, low, high)
+		}
+	}
+
+	return nil
+}
+
+func transitionPeriod(period time.Duration) int {
+	return int(period / time.Millisecond)
+}
@@ -0,0 +1,178 @@
+package tplink
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// bulbModules emulates a bulb. The light state is kept so transitions are
+// reflected in the following get_light_state.
+func bulbModules(model string, dimmable int, color int, colorTemp int) mockModules {
+	state := map[string]interface{}{
+		"on_off":     1,
+		"mode":       "normal",
+		"hue":        0,
+		"saturation": 0,
+		"color_temp": 2700,
+		"brightness": 100,
+	}
+
+	current := func() map[string]interface{} {
+		out := map[string]interface{}{"err_code": 0}
+		for k, v := range state {
+			out[k] = v
+		}
+		return out
+	}
+
+	return mockModules{
+		"system": {
+			"get_sysinfo": func(map[string]interface{}) interface{} {
+				return map[string]interface{}{
+					"err_code":               0,
+					"alias":                  "Mock Bulb",
+					"mic_type":               "IOT.SMARTBULB",
+					"model":                  model,
+					"is_dimmable":            dimmable,
+					"is_color":               color,
+					"is_variable_color_temp": colorTemp,
+					"light_state":            current(),
+				}
+			},
+		},
+		lightingService: {
+			"get_light_state": func(map[string]interface{}) interface{} {
+				return current()
+			},
+			"transition_light_state": func(params map[string]interface{}) interface{} {
+				for k, v := range params {
+					if _, ok := state[k]; ok {
+						state[k] = v
+					}
+				}
+				return current()
+			},
+		},
+	}
+}
+
+func TestBulb(t *testing.T) {
+	api, transport := newMockManager(bulbModules("KL130(US)", 1, 1, 1).handler)
+
+	config := BulbConfig("mock")
+	bulb, err := api.Bulb(devices.NewDevice(&config))
+	if err != nil {
+		t.Fatalf("failed to access bulb: %s", err)
+	}
+
+	if !bulb.IsColor() || !bulb.IsDimmable() || !bulb.IsVariableColorTemp() {
+		t.Fatalf("unexpected bulb capabilities")
+	}
+
+	state, err := bulb.SetHSV(240, 100, 50, 500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to set color: %s", err)
+	}
+	if state.Hue != 240 || state.Saturation != 100 || state.Brightness != 50 || state.ColorTemp != 0 {
+		t.Fatalf("unexpected light state %+v", state)
+	}
+
+	requests := transport.Requests()
+	expected := "{\"smartlife.iot.smartbulb.lightingservice\":{\"transition_light_state\":" +
+		"{\"hue\":240,\"saturation\":100,\"color_temp\":0,\"brightness\":50," +
+		"\"transition_period\":500,\"ignore_default\":1}}}"
+	if requests[len(requests)-1] != expected {
+		t.Fatalf("unexpected request '%s'", requests[len(requests)-1])
+	}
+
+	if _, err = bulb.SetColorTemp(6500, 0); err != nil {
+		t.Fatalf("failed to set color temperature: %s", err)
+	}
+	if _, err = bulb.SetOn(false, time.Second); err != nil {
+		t.Fatalf("failed to turn bulb off: %s", err)
+	}
+
+	state, err = bulb.LightState()
+	if err != nil {
+		t.Fatalf("failed to get light state: %s", err)
+	}
+	if state.IsOn() || state.ColorTemp != 6500 {
+		t.Fatalf("unexpected light state %+v", state)
+	}
+}
+
+func TestBulbCapabilities(t *testing.T) {
+	api, transport := newMockManager(bulbModules("KL110(US)", 1, 0, 0).handler)
+
+	config := BulbConfig("mock")
+	bulb, err := api.Bulb(devices.NewDevice(&config))
+	if err != nil {
+		t.Fatalf("failed to access bulb: %s", err)
+	}
+
+	sent := len(transport.Requests())
+
+	if _, err = bulb.SetHSV(120, 50, 50, 0); !errors.Is(err, ErrUnsupportedFeature) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = bulb.SetColorTemp(4000, 0); !errors.Is(err, ErrUnsupportedFeature) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = bulb.SetBrightness(101, 0); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(transport.Requests()) != sent {
+		t.Fatalf("rejected operations were sent to the bulb")
+	}
+
+	if _, err = bulb.SetBrightness(30, 0); err != nil {
+		t.Fatalf("failed to set brightness: %s", err)
+	}
+}
+
+func TestBulbColorTempRange(t *testing.T) {
+	api, _ := newMockManager(bulbModules("KL120(US)", 1, 0, 1).handler)
+
+	config := BulbConfig("mock")
+	bulb, err := api.Bulb(devices.NewDevice(&config))
+	if err != nil {
+		t.Fatalf("failed to access bulb: %s", err)
+	}
+
+	if low, high := bulb.ColorTempRange(); low != 2700 || high != 5000 {
+		t.Fatalf("unexpected color temperature range %d-%d", low, high)
+	}
+	if _, err = bulb.SetColorTemp(6500, 0); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestColorTempRangeLookup(t *testing.T) {
+	ranges := map[string][2]int{
+		"KL120(EU)": {2700, 6500},
+		"KL120(US)": {2700, 5000},
+		"KL120(UK)": {2700, 5000},
+		"KL120":     {2700, 5000},
+		"KL130(EU)": {2500, 9000},
+		"XX100(US)": defaultColorTempRange,
+	}
+	for model, expected := range ranges {
+		for i := 0; i < 10; i++ {
+			if r := colorTempRange(model); r != expected {
+				t.Fatalf("unexpected range %v for %s", r, model)
+			}
+		}
+	}
+}
+
+func TestBulbOnPlug(t *testing.T) {
+	api, _ := newMockManager(plugHandler)
+
+	config := PlugConfig("mock")
+	if _, err := api.Bulb(devices.NewDevice(&config)); !errors.Is(err, ErrUnsupportedFeature) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
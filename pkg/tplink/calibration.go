@@ -0,0 +1,203 @@
+package tplink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var ErrCalibrationFailed = errors.New("calibration did not improve the readings")
+
+type CalibrationOption func(*CalibrationOptions)
+
+type CalibrationOptions struct {
+	// Samples is the number of readings averaged before and after the new
+	// gains are applied.
+	Samples        int
+	SampleInterval time.Duration
+}
+
+func WithSamples(samples int) CalibrationOption {
+	return func(o *CalibrationOptions) {
+		o.Samples = samples
+	}
+}
+
+func WithSampleInterval(interval time.Duration) CalibrationOption {
+	return func(o *CalibrationOptions) {
+		o.SampleInterval = interval
+	}
+}
+
+func DefaultCalibrationOptions() *CalibrationOptions {
+	return &CalibrationOptions{
+		Samples:        5,
+		SampleInterval: time.Second,
+	}
+}
+
+// CalibrationReference is the voltage in V and the power in W of a resistive
+// load as measured by a reference meter.
+type CalibrationReference struct {
+	Voltage float64
+	Power   float64
+}
+
+// Calibration records a calibration. Previous holds the gains before the
+// calibration so it can be undone with EMeter.Rollback.
+type Calibration struct {
+	Reference CalibrationReference
+	Previous  Gains
+	Applied   Gains
+	Before    RealTimeEnergy
+	After     RealTimeEnergy
+}
+
+func (e *EMeter) Gains() (*Gains, error) {
+	return e.GainsContext(context.Background())
+}
+
+func (e *EMeter) GainsContext(ctx context.Context) (*Gains, error) {
+	var gains EMeterGains
+
+	if err := e.mgr.DoContext(ctx, e.device, &gains); err != nil {
+		return nil, err
+	}
+
+	g := gains.GetGains()
+	return &g, nil
+}
+
+func (e *EMeter) SetGains(g Gains) error {
+	return e.SetGainsContext(context.Background(), g)
+}
+
+func (e *EMeter) SetGainsContext(ctx context.Context, g Gains) error {
+	if g.VGain <= 0 || g.IGain <= 0 {
+		return fmt.Errorf("%w: gains %d/%d", ErrInvalidParameter, g.VGain, g.IGain)
+	}
+
+	var gains EMeterSetGains
+	gains.SetGains(g)
+
+	return e.mgr.DoContext(ctx, e.device, &gains)
+}
+
+func (e *EMeter) Rollback(c *Calibration) error {
+	return e.RollbackContext(context.Background(), c)
+}
+
+// RollbackContext restores the gains the device had before the calibration.
+func (e *EMeter) RollbackContext(ctx context.Context, c *Calibration) error {
+	return e.SetGainsContext(ctx, c.Previous)
+}
+
+func (e *EMeter) Calibrate(ref CalibrationReference, opts ...CalibrationOption) (*Calibration, error) {
+	return e.CalibrateContext(context.Background(), ref, opts...)
+}
+
+// CalibrateContext scales the gains by the ratio of the reference to the
+// averaged readings of the device, with the reference load attached. The
+// new gains are kept only when they bring the readings closer to the
+// reference, otherwise the previous gains are restored and
+// ErrCalibrationFailed is returned along with the calibration.
+func (e *EMeter) CalibrateContext(ctx context.Context, ref CalibrationReference, opts ...CalibrationOption) (*Calibration, error) {
+	options := DefaultCalibrationOptions()
+	for _, option := range opts {
+		option(options)
+	}
+
+	if ref.Voltage <= 0 || ref.Power <= 0 {
+		return nil, fmt.Errorf("%w: reference %.2fV %.2fW", ErrInvalidParameter, ref.Voltage, ref.Power)
+	}
+	if options.Samples < 1 {
+		return nil, fmt.Errorf("%w: %d samples", ErrInvalidParameter, options.Samples)
+	}
+
+	previous, err := e.GainsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Calibration{Reference: ref, Previous: *previous}
+
+	if c.Before, err = e.sample(ctx, options); err != nil {
+		return nil, err
+	}
+	if c.Before.Voltage <= 0 || c.Before.Power <= 0 {
+		return nil, fmt.Errorf("%w: no load attached, read %.2fV %.2fW",
+			ErrInvalidParameter, c.Before.Voltage, c.Before.Power)
+	}
+
+	// The current is derived from voltage and power on both sides, the load
+	// is resistive so the power factor is one.
+	current := c.Before.Power / c.Before.Voltage
+	c.Applied = Gains{
+		VGain: int(math.Round(float64(previous.VGain) * ref.Voltage / c.Before.Voltage)),
+		IGain: int(math.Round(float64(previous.IGain) * (ref.Power / ref.Voltage) / current)),
+	}
+
+	if err = e.SetGainsContext(ctx, c.Applied); err != nil {
+		return nil, err
+	}
+
+	if c.After, err = e.sample(ctx, options); err != nil {
+		return c, e.rollback(c, err)
+	}
+
+	if readingError(c.After, ref) > readingError(c.Before, ref) {
+		return c, e.rollback(c, ErrCalibrationFailed)
+	}
+
+	e.mgr.Logger().Info("calibrated electricity meter",
+		zap.String("device", deviceAddress(e.device)),
+		zap.Int("vgain", c.Applied.VGain),
+		zap.Int("igain", c.Applied.IGain),
+		zap.Int("previous_vgain", c.Previous.VGain),
+		zap.Int("previous_igain", c.Previous.IGain))
+
+	return c, nil
+}
+
+// rollback restores the previous gains after a failed calibration. The
+// context of the calibration may be done at this point so it is not used.
+func (e *EMeter) rollback(c *Calibration, cause error) error {
+	if err := e.Rollback(c); err != nil {
+		return fmt.Errorf("%w, restoring the previous gains failed: %s", cause, err)
+	}
+	return cause
+}
+
+// sample averages the voltage and power readings.
+func (e *EMeter) sample(ctx context.Context, options *CalibrationOptions) (RealTimeEnergy, error) {
+	var avg RealTimeEnergy
+
+	for i := 0; i < options.Samples; i++ {
+		if i > 0 {
+			if err := wait(ctx, options.SampleInterval); err != nil {
+				return avg, err
+			}
+		}
+
+		energy, err := e.RealtimeContext(ctx)
+		if err != nil {
+			return avg, err
+		}
+		avg.Voltage += energy.Voltage
+		avg.Power += energy.Power
+	}
+
+	avg.Voltage /= float64(options.Samples)
+	avg.Power /= float64(options.Samples)
+	avg.HasVoltage, avg.HasPower = true, true
+
+	return avg, nil
+}
+
+func readingError(reading RealTimeEnergy, ref CalibrationReference) float64 {
+	return math.Abs(reading.Power-ref.Power)/ref.Power + math.Abs(reading.Voltage-ref.Voltage)/ref.Voltage
+}
@@ -0,0 +1,108 @@
+package tplink
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// calibrationModules emulates a meter with a 120V 100W load attached which
+// reads high by the ratio of its gains to the nominal gains. With inverse
+// set the readings fall as the gains rise.
+func calibrationModules(gains *Gains, inverse bool) mockModules {
+	const nominal = 10000.0
+
+	scale := func(gain int) float64 {
+		if inverse {
+			return nominal / float64(gain)
+		}
+		return float64(gain) / nominal
+	}
+
+	modules := plugModules()
+	modules["emeter"]["get_realtime"] = func(map[string]interface{}) interface{} {
+		voltage := 120.0 * scale(gains.VGain)
+		current := 100.0 / 120.0 * scale(gains.IGain)
+		return map[string]interface{}{
+			"err_code":   0,
+			"voltage_mv": voltage * 1000,
+			"current_ma": current * 1000,
+			"power_mw":   voltage * current * 1000,
+			"total_wh":   0,
+		}
+	}
+	modules["emeter"]["get_vgain_igain"] = func(map[string]interface{}) interface{} {
+		return map[string]interface{}{"err_code": 0, "vgain": gains.VGain, "igain": gains.IGain}
+	}
+	modules["emeter"]["set_vgain_igain"] = func(params map[string]interface{}) interface{} {
+		v, _ := params["vgain"].(float64)
+		i, _ := params["igain"].(float64)
+		gains.VGain, gains.IGain = int(v), int(i)
+		return map[string]interface{}{"err_code": 0}
+	}
+	return modules
+}
+
+func TestCalibrate(t *testing.T) {
+	gains := Gains{VGain: 10600, IGain: 10300}
+	api, _ := newMockManager(calibrationModules(&gains, false).handler)
+
+	config := PlugConfig("mock")
+	meter, err := api.ElectricityMeter(devices.NewDevice(&config, devices.WithFeatures([]string{"ENE"})))
+	if err != nil {
+		t.Fatalf("failed to access emeter: %s", err)
+	}
+
+	c, err := meter.Calibrate(CalibrationReference{Voltage: 120, Power: 100},
+		WithSamples(2), WithSampleInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to calibrate: %s", err)
+	}
+
+	if c.Previous.VGain != 10600 || c.Previous.IGain != 10300 {
+		t.Fatalf("unexpected previous gains %+v", c.Previous)
+	}
+	if gains.VGain != 10000 || gains.IGain != 10000 || c.Applied != gains {
+		t.Fatalf("unexpected gains %+v", gains)
+	}
+	if math.Abs(c.After.Power-100) > 0.1 || math.Abs(c.Before.Power-109.18) > 0.1 {
+		t.Fatalf("unexpected readings %.2fW -> %.2fW", c.Before.Power, c.After.Power)
+	}
+
+	if err = meter.Rollback(c); err != nil {
+		t.Fatalf("failed to roll back calibration: %s", err)
+	}
+	if gains.VGain != 10600 || gains.IGain != 10300 {
+		t.Fatalf("unexpected gains after rollback %+v", gains)
+	}
+}
+
+func TestCalibrateRollback(t *testing.T) {
+	gains := Gains{VGain: 9400, IGain: 9400}
+	api, _ := newMockManager(calibrationModules(&gains, true).handler)
+
+	config := PlugConfig("mock")
+	meter, err := api.ElectricityMeter(devices.NewDevice(&config, devices.WithFeatures([]string{"ENE"})))
+	if err != nil {
+		t.Fatalf("failed to access emeter: %s", err)
+	}
+
+	c, err := meter.Calibrate(CalibrationReference{Voltage: 120, Power: 100},
+		WithSamples(1))
+	if !errors.Is(err, ErrCalibrationFailed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c == nil || c.Applied == c.Previous {
+		t.Fatalf("unexpected calibration %+v", c)
+	}
+	if gains.VGain != 9400 || gains.IGain != 9400 {
+		t.Fatalf("previous gains were not restored %+v", gains)
+	}
+
+	if _, err = meter.Calibrate(CalibrationReference{Voltage: 120}); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package tplink
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+type childContext struct {
+	ChildIds []string `json:"child_ids"`
+}
+
+// childId returns the full id of an outlet. Some firmware reports only the
+// index of the outlet which then has to be prefixed with the parent id.
+func childId(parent *devices.Device, id string) string {
+	if parent.DeviceId() != "" && !strings.HasPrefix(id, parent.DeviceId()) {
+		return parent.DeviceId() + id
+	}
+	return id
+}
+
+// withChildContext adds the context naming the outlet to a request. A
+// request which already carries a context is left unchanged.
+func withChildContext(s []byte, id string) ([]byte, error) {
+	var request map[string]json.RawMessage
+	if err := json.Unmarshal(s, &request); err != nil {
+		return nil, err
+	}
+
+	if _, ok := request["context"]; ok {
+		return s, nil
+	}
+
+	ctx, err := json.Marshal(childContext{ChildIds: []string{id}})
+	if err != nil {
+		return nil, err
+	}
+	request["context"] = ctx
+
+	return json.Marshal(request)
+}
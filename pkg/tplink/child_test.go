@@ -0,0 +1,124 @@
+package tplink
+
+import (
+	"testing"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+const stripId = "8006000000000000000000000000000000000001"
+
+// stripHandler emulates an HS300 with three outlets. The outlet a request is
+// addressed to is taken from the context of the request.
+func stripHandler() mockHandler {
+	relays := map[string]int{stripId + "00": 1, stripId + "01": 0, "02": 0}
+	power := map[string]float64{stripId + "00": 10.0, stripId + "01": 0.0, "02": 0.0}
+	var targets []string
+
+	modules := mockModules{
+		"system": {
+			"get_sysinfo": func(map[string]interface{}) interface{} {
+				children := []map[string]interface{}{
+					{"id": stripId + "00", "alias": "Outlet 1", "state": relays[stripId+"00"], "on_time": 120},
+					{"id": stripId + "01", "alias": "Outlet 2", "state": relays[stripId+"01"], "on_time": 0},
+					{"id": "02", "alias": "Outlet 3", "state": relays["02"], "on_time": 0},
+				}
+				return map[string]interface{}{
+					"err_code":  0,
+					"alias":     "Mock Strip",
+					"deviceId":  stripId,
+					"feature":   "TIM:ENE",
+					"model":     "HS300(US)",
+					"type":      "IOT.SMARTPLUGSWITCH",
+					"child_num": len(children),
+					"children":  children,
+				}
+			},
+			"set_relay_state": func(params map[string]interface{}) interface{} {
+				state, _ := params["state"].(float64)
+				for _, id := range targets {
+					relays[id] = int(state)
+				}
+				return map[string]interface{}{"err_code": 0}
+			},
+		},
+		"emeter": {
+			"get_realtime": func(map[string]interface{}) interface{} {
+				if len(targets) != 1 {
+					return map[string]interface{}{"err_code": -3, "err_msg": "invalid argument"}
+				}
+				return map[string]interface{}{"err_code": 0, "power": power[targets[0]]}
+			},
+		},
+	}
+
+	return func(d devices.Addressable, req map[string]interface{}) interface{} {
+		targets = nil
+		if ctx, ok := req["context"].(map[string]interface{}); ok {
+			ids, _ := ctx["child_ids"].([]interface{})
+			for _, id := range ids {
+				if s, ok := id.(string); ok {
+					targets = append(targets, s)
+				}
+			}
+		}
+		return modules.handler(d, req)
+	}
+}
+
+func TestChildDevices(t *testing.T) {
+	api, transport := newMockManager(stripHandler())
+
+	config := PlugConfig("mock")
+	strip, err := api.LoadDevice(&config)
+	if err != nil {
+		t.Fatalf("failed to load device: %s", err)
+	}
+
+	children := strip.Children()
+	if len(children) != 3 {
+		t.Fatalf("unexpected number of outlets %d", len(children))
+	}
+
+	first := children[0]
+	if first.Alias() != "Outlet 1" || !first.RelayState() || first.OnTime() != 120 {
+		t.Fatalf("unexpected outlet %s (%v, %d)", first.Alias(), first.RelayState(), first.OnTime())
+	}
+	if first.Parent() != strip || first.Address() != strip.Address() || first.Port() != strip.Port() {
+		t.Fatalf("outlet is not addressed through its parent")
+	}
+	if children[2].ChildId() != stripId+"02" {
+		t.Fatalf("unexpected outlet id '%s'", children[2].ChildId())
+	}
+
+	if err = api.On(children[1]); err != nil {
+		t.Fatalf("failed to turn outlet on: %s", err)
+	}
+
+	requests := transport.Requests()
+	expected := "{\"context\":{\"child_ids\":[\"" + stripId + "01\"]}," +
+		"\"system\":{\"set_relay_state\":{\"state\":1}}}"
+	if requests[len(requests)-1] != expected {
+		t.Fatalf("unexpected request '%s'", requests[len(requests)-1])
+	}
+
+	info, err := api.SystemInfo(strip)
+	if err != nil {
+		t.Fatalf("failed to load SystemInfo: %s", err)
+	}
+	if info.Children[0].RelayState != 1 || info.Children[1].RelayState != 1 || info.Children[2].RelayState != 0 {
+		t.Fatalf("unexpected outlet states %+v", info.Children)
+	}
+
+	meter, err := api.ElectricityMeter(first)
+	if err != nil {
+		t.Fatalf("failed to access outlet emeter: %s", err)
+	}
+	energy, err := meter.Realtime()
+	if err != nil {
+		t.Fatalf("failed to read outlet emeter: %s", err)
+	}
+	if energy.Power != 10.0 {
+		t.Fatalf("unexpected outlet power %f", energy.Power)
+	}
+}
@@ -0,0 +1,165 @@
+package tplink
+
+import (
+	"context"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+const (
+	timeModule     = "time"
+	bulbTimeModule = "smartlife.iot.common.timesetting"
+)
+
+// DeviceTime is the wall clock of the device in its configured timezone.
+type DeviceTime struct {
+	errorCode
+	Year   int `json:"year"`
+	Month  int `json:"month"`
+	Day    int `json:"mday"`
+	Hour   int `json:"hour"`
+	Minute int `json:"min"`
+	Second int `json:"sec"`
+}
+
+// In interprets the wall clock of the device in loc.
+func (t *DeviceTime) In(loc *time.Location) time.Time {
+	return time.Date(t.Year, time.Month(t.Month), t.Day, t.Hour, t.Minute, t.Second, 0, loc)
+}
+
+type DeviceTimezone struct {
+	errorCode
+	Index     int    `json:"index"`
+	ZoneStr   string `json:"zone_str,omitempty"`
+	TzStr     string `json:"tz_str,omitempty"`
+	DstOffset int    `json:"dst_offset,omitempty"`
+}
+
+// Location returns the IANA location for the timezone index.
+func (tz *DeviceTimezone) Location() (*time.Location, error) {
+	return TimezoneLocation(tz.Index)
+}
+
+type setTimezone struct {
+	Year   int `json:"year"`
+	Month  int `json:"month"`
+	Day    int `json:"mday"`
+	Hour   int `json:"hour"`
+	Minute int `json:"min"`
+	Second int `json:"sec"`
+	Index  int `json:"index"`
+}
+
+func clockModule(d *devices.Device) string {
+	if d.DeviceType() == devices.BulbDevice {
+		return bulbTimeModule
+	}
+	return timeModule
+}
+
+func (m *DeviceManager) ClockDrift(d *devices.Device) (time.Duration, error) {
+	return m.ClockDriftContext(context.Background(), d)
+}
+
+// ClockDriftContext returns how far the clock of the device is ahead of the
+// host clock, negative when it is behind. The device reports whole seconds
+// so drift below a second is not detected.
+func (m *DeviceManager) ClockDriftContext(ctx context.Context, d *devices.Device) (time.Duration, error) {
+	module := clockModule(d)
+	batch := NewBatch().
+		Add(module, "get_time", nil).
+		Add(module, "get_timezone", nil)
+
+	sent := time.Now()
+	res, err := m.SendBatchContext(ctx, d, batch)
+	if err != nil {
+		return 0, err
+	}
+	received := time.Now()
+
+	var now DeviceTime
+	if err = res.Decode(module, "get_time", &now); err != nil {
+		return 0, err
+	}
+
+	var tz DeviceTimezone
+	if err = res.Decode(module, "get_timezone", &tz); err != nil {
+		return 0, err
+	}
+
+	loc, err := tz.Location()
+	if err != nil {
+		return 0, err
+	}
+
+	host := sent.Add(received.Sub(sent) / 2).Truncate(time.Second)
+	return now.In(loc).Sub(host), nil
+}
+
+func (m *DeviceManager) SetTimezone(d *devices.Device, index int, now time.Time) error {
+	return m.SetTimezoneContext(context.Background(), d, index, now)
+}
+
+// SetTimezoneContext sets the timezone index of the device together with its
+// clock, now is converted to the timezone before it is sent.
+func (m *DeviceManager) SetTimezoneContext(ctx context.Context, d *devices.Device, index int, now time.Time) error {
+	loc, err := TimezoneLocation(index)
+	if err != nil {
+		return err
+	}
+	now = now.In(loc)
+
+	params := setTimezone{
+		Year:   now.Year(),
+		Month:  int(now.Month()),
+		Day:    now.Day(),
+		Hour:   now.Hour(),
+		Minute: now.Minute(),
+		Second: now.Second(),
+		Index:  index,
+	}
+
+	return m.call(ctx, d, clockModule(d), "set_timezone", params, nil)
+}
+
+func (m *DeviceManager) SyncTime(d *devices.Device, zone string) error {
+	return m.SyncTimeContext(context.Background(), d, zone)
+}
+
+// SyncTimeContext sets the timezone of the device from an IANA zone name,
+// see TimezoneIndex, and its clock to the host clock.
+func (m *DeviceManager) SyncTimeContext(ctx context.Context, d *devices.Device, zone string) error {
+	index, err := TimezoneIndex(zone)
+	if err != nil {
+		return err
+	}
+
+	return m.SetTimezoneContext(ctx, d, index, time.Now())
+}
+
+func (m *DeviceManager) Time(d *devices.Device) (*DeviceTime, error) {
+	return m.TimeContext(context.Background(), d)
+}
+
+func (m *DeviceManager) TimeContext(ctx context.Context, d *devices.Device) (*DeviceTime, error) {
+	var now DeviceTime
+	if err := m.call(ctx, d, clockModule(d), "get_time", nil, &now); err != nil {
+		return nil, err
+	}
+
+	return &now, nil
+}
+
+func (m *DeviceManager) Timezone(d *devices.Device) (*DeviceTimezone, error) {
+	return m.TimezoneContext(context.Background(), d)
+}
+
+func (m *DeviceManager) TimezoneContext(ctx context.Context, d *devices.Device) (*DeviceTimezone, error) {
+	var tz DeviceTimezone
+	if err := m.call(ctx, d, clockModule(d), "get_timezone", nil, &tz); err != nil {
+		return nil, err
+	}
+
+	return &tz, nil
+}
@@ -0,0 +1,132 @@
+package tplink
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// clockModules emulates a device clock running ahead of the host clock by
+// drift in the timezone with the given index.
+func clockModules(module string, index int, drift time.Duration) mockModules {
+	var set map[string]interface{}
+
+	return mockModules{
+		module: {
+			"get_time": func(map[string]interface{}) interface{} {
+				loc, _ := TimezoneLocation(index)
+				now := time.Now().Add(drift).In(loc)
+				return map[string]interface{}{
+					"err_code": 0,
+					"year":     now.Year(),
+					"month":    int(now.Month()),
+					"mday":     now.Day(),
+					"hour":     now.Hour(),
+					"min":      now.Minute(),
+					"sec":      now.Second(),
+				}
+			},
+			"get_timezone": func(map[string]interface{}) interface{} {
+				return map[string]interface{}{"err_code": 0, "index": index}
+			},
+			"set_timezone": func(params map[string]interface{}) interface{} {
+				set = params
+				v, _ := set["index"].(float64)
+				index = int(v)
+				drift = 0
+				return map[string]interface{}{"err_code": 0}
+			},
+		},
+	}
+}
+
+func TestClockDrift(t *testing.T) {
+	api, _ := newMockManager(clockModules(timeModule, 90, 90*time.Second).handler)
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	drift, err := api.ClockDrift(device)
+	if err != nil {
+		t.Fatalf("failed to get clock drift: %s", err)
+	}
+	if drift < 88*time.Second || drift > 92*time.Second {
+		t.Fatalf("unexpected clock drift %s", drift)
+	}
+
+	if err = api.SyncTime(device, "Europe/Berlin"); err != nil {
+		t.Fatalf("failed to sync time: %s", err)
+	}
+
+	tz, err := api.Timezone(device)
+	if err != nil {
+		t.Fatalf("failed to get timezone: %s", err)
+	}
+	if tz.Index != 41 {
+		t.Fatalf("unexpected timezone index %d", tz.Index)
+	}
+
+	if drift, err = api.ClockDrift(device); err != nil {
+		t.Fatalf("failed to get clock drift: %s", err)
+	}
+	if drift < -2*time.Second || drift > 2*time.Second {
+		t.Fatalf("unexpected clock drift %s", drift)
+	}
+}
+
+func TestClockBulb(t *testing.T) {
+	api, transport := newMockManager(clockModules(bulbTimeModule, 18, 0).handler)
+
+	config := BulbConfig("mock")
+	device := devices.NewDevice(&config)
+
+	now, err := api.Time(device)
+	if err != nil {
+		t.Fatalf("failed to get time: %s", err)
+	}
+	if now.Year < 2000 {
+		t.Fatalf("unexpected time %+v", now)
+	}
+
+	requests := transport.Requests()
+	if len(requests) != 1 || requests[0] != "{\"smartlife.iot.common.timesetting\":{\"get_time\":{}}}" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+}
+
+func TestTimezoneIndex(t *testing.T) {
+	zones := map[string]int{
+		"Asia/Kolkata":     75,
+		"America/New_York": 18,
+		"Europe/Berlin":    41,
+		"Europe/London":    39,
+		"UTC":              38,
+	}
+
+	for zone, expected := range zones {
+		index, err := TimezoneIndex(zone)
+		if err != nil {
+			t.Fatalf("failed to find timezone %s: %s", zone, err)
+		}
+		if index != expected {
+			t.Fatalf("unexpected index %d for %s", index, zone)
+		}
+	}
+
+	if _, err := TimezoneIndex("Mars/Olympus_Mons"); !errors.Is(err, ErrUnknownTimezone) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := TimezoneLocation(len(timezones)); !errors.Is(err, ErrUnknownTimezone) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestTimezoneLocations(t *testing.T) {
+	for index := range timezones {
+		if _, err := TimezoneLocation(index); err != nil {
+			t.Fatalf("failed to load timezone %d: %s", index, err)
+		}
+	}
+}
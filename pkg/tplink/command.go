@@ -0,0 +1,128 @@
+package tplink
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// Command is a request addressed to a single method of a module, such as
+// SystemReboot for 'system.reboot'. ErrorCode reports the 'err_code' once
+// the response has been decoded into the command.
+type Command interface {
+	Module() string
+	Method() string
+	ErrorCode() int
+}
+
+var (
+	_ Command = (*AmbientLightSensorBrightness)(nil)
+	_ Command = (*AmbientLightSensorConfig)(nil)
+	_ Command = (*AmbientLightSensorEnable)(nil)
+	_ Command = (*AmbientLightSensorLevel)(nil)
+	_ Command = (*AntiTheftAddRule)(nil)
+	_ Command = (*AntiTheftDeleteRule)(nil)
+	_ Command = (*AntiTheftDeleteRules)(nil)
+	_ Command = (*AntiTheftEditRule)(nil)
+	_ Command = (*AntiTheftEnable)(nil)
+	_ Command = (*AntiTheftRules)(nil)
+	_ Command = (*BulbLightState)(nil)
+	_ Command = (*BulbTransition)(nil)
+	_ Command = (*CountdownAddRule)(nil)
+	_ Command = (*CountdownDeleteRules)(nil)
+	_ Command = (*CountdownEditRule)(nil)
+	_ Command = (*CountdownRules)(nil)
+	_ Command = (*DeviceInfo)(nil)
+	_ Command = (*DimmerBrightness)(nil)
+	_ Command = (*DimmerDoubleClickAction)(nil)
+	_ Command = (*DimmerFadeOffTime)(nil)
+	_ Command = (*DimmerFadeOnTime)(nil)
+	_ Command = (*DimmerGentleOffTime)(nil)
+	_ Command = (*DimmerGentleOnTime)(nil)
+	_ Command = (*DimmerGetParameters)(nil)
+	_ Command = (*ElectricityMeterInfo)(nil)
+	_ Command = (*EMeterDailyStats)(nil)
+	_ Command = (*EMeterEraseStats)(nil)
+	_ Command = (*EMeterGains)(nil)
+	_ Command = (*EMeterMonthlyStats)(nil)
+	_ Command = (*EMeterSetGains)(nil)
+	_ Command = (*LightingEffectSet)(nil)
+	_ Command = (*LightStripSegments)(nil)
+	_ Command = (*MotionSensorColdTime)(nil)
+	_ Command = (*MotionSensorConfig)(nil)
+	_ Command = (*MotionSensorEnable)(nil)
+	_ Command = (*MotionSensorSensitivity)(nil)
+	_ Command = (*NetworkScan)(nil)
+	_ Command = (*NetworkSettings)(nil)
+	_ Command = (*SystemAlias)(nil)
+	_ Command = (*SystemDeviceId)(nil)
+	_ Command = (*SystemDownloadFirmware)(nil)
+	_ Command = (*SystemDownloadState)(nil)
+	_ Command = (*SystemFlashFirmware)(nil)
+	_ Command = (*SystemHardwareId)(nil)
+	_ Command = (*SystemLedState)(nil)
+	_ Command = (*SystemLocation)(nil)
+	_ Command = (*SystemReboot)(nil)
+	_ Command = (*SystemRelayState)(nil)
+	_ Command = (*SystemReset)(nil)
+)
+
+func Execute[Req Command, Resp any](m *DeviceManager, d devices.Addressable, req Req) (*Resp, error) {
+	return ExecuteContext[Req, Resp](context.Background(), m, d, req)
+}
+
+// ExecuteContext sends req to the device and decodes the response into a new
+// Resp. Errors reported by the device are returned as a ProtocolError.
+func ExecuteContext[Req Command, Resp any](ctx context.Context, m *DeviceManager, d devices.Addressable, req Req) (*Resp, error) {
+	res, err := m.MarshalContext(ctx, d, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = decodeResponse(d, res, req.Module(), req.Method()); err != nil {
+		return nil, err
+	}
+
+	resp := new(Resp)
+	if err = json.Unmarshal(res, resp); err != nil {
+		return nil, err
+	}
+
+	if cmd, ok := any(resp).(Command); ok {
+		if err = commandError(d, cmd); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+func (m *DeviceManager) Do(d devices.Addressable, cmd Command) error {
+	return m.DoContext(context.Background(), d, cmd)
+}
+
+// DoContext sends cmd to the device and decodes the response back into cmd,
+// for commands which share their request and response type.
+func (m *DeviceManager) DoContext(ctx context.Context, d devices.Addressable, cmd Command) error {
+	res, err := m.MarshalContext(ctx, d, cmd)
+	if err != nil {
+		return err
+	}
+
+	if _, err = decodeResponse(d, res, cmd.Module(), cmd.Method()); err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(res, cmd); err != nil {
+		return err
+	}
+
+	return commandError(d, cmd)
+}
+
+func commandError(d devices.Addressable, cmd Command) error {
+	return protocolError(d, cmd.Module(), cmd.Method(), errorCode{
+		ErrorCode: cmd.ErrorCode(),
+	})
+}
@@ -0,0 +1,104 @@
+package tplink
+
+import (
+	"testing"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// cloudInfo is defined the way a caller would define a command the library
+// does not know about.
+type cloudInfo struct {
+	Cloud struct {
+		Info struct {
+			ErrorCode int    `json:"err_code,omitempty"`
+			Server    string `json:"server,omitempty"`
+			Username  string `json:"username,omitempty"`
+		} `json:"get_info"`
+	} `json:"cnCloud"`
+}
+
+func (c *cloudInfo) ErrorCode() int {
+	return c.Cloud.Info.ErrorCode
+}
+
+func (c *cloudInfo) Method() string {
+	return "get_info"
+}
+
+func (c *cloudInfo) Module() string {
+	return "cnCloud"
+}
+
+func cloudModules() mockModules {
+	modules := plugModules()
+	modules["cnCloud"] = map[string]mockMethod{
+		"get_info": func(map[string]interface{}) interface{} {
+			return map[string]interface{}{
+				"err_code": 0,
+				"server":   "n-devs.tplinkcloud.com",
+				"username": "user@example.com",
+			}
+		},
+	}
+	return modules
+}
+
+func TestExecute(t *testing.T) {
+	api, transport := newMockManager(cloudModules().handler)
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	info, err := Execute[*cloudInfo, cloudInfo](api, device, &cloudInfo{})
+	if err != nil {
+		t.Fatalf("failed to execute command: %s", err)
+	}
+	if info.Cloud.Info.Server != "n-devs.tplinkcloud.com" {
+		t.Fatalf("unexpected server '%s'", info.Cloud.Info.Server)
+	}
+
+	requests := transport.Requests()
+	if len(requests) != 1 || requests[0] != "{\"cnCloud\":{\"get_info\":{}}}" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+
+	var reboot SystemReboot
+	reboot.SetDelay(1)
+
+	if _, err = Execute[*SystemReboot, map[string]interface{}](api, device, &reboot); err != nil {
+		t.Fatalf("failed to execute command: %s", err)
+	}
+}
+
+func TestExecuteUnsupported(t *testing.T) {
+	api, _ := newMockManager(plugHandler)
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	if _, err := Execute[*cloudInfo, cloudInfo](api, device, &cloudInfo{}); !IsModuleNotSupported(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDo(t *testing.T) {
+	api, _ := newMockManager(cloudModules().handler)
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	var info cloudInfo
+	if err := api.Do(device, &info); err != nil {
+		t.Fatalf("failed to execute command: %s", err)
+	}
+	if info.Cloud.Info.Username != "user@example.com" {
+		t.Fatalf("unexpected username '%s'", info.Cloud.Info.Username)
+	}
+
+	var led SystemLedState
+	led.SetState(true)
+	if err := api.Do(device, &led); !IsMethodNotSupported(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package tplink
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+func (m *DeviceManager) AddCountdownRule(d *devices.Device, rule CountdownRule) (string, error) {
+	return m.AddCountdownRuleContext(context.Background(), d, rule)
+}
+
+// AddCountdownRuleContext stores the rule on the device and returns its id.
+// Devices keep a single countdown, see StartCountdown.
+func (m *DeviceManager) AddCountdownRuleContext(ctx context.Context, d *devices.Device, rule CountdownRule) (string, error) {
+	if err := validateCountdown(&rule); err != nil {
+		return "", err
+	}
+
+	var r CountdownAddRule
+	r.SetRule(rule)
+
+	if err := m.DoContext(ctx, d, &r); err != nil {
+		return "", err
+	}
+
+	return r.GetId(), nil
+}
+
+func (m *DeviceManager) CountdownRules(d *devices.Device) ([]CountdownRule, error) {
+	return m.CountdownRulesContext(context.Background(), d)
+}
+
+func (m *DeviceManager) CountdownRulesContext(ctx context.Context, d *devices.Device) ([]CountdownRule, error) {
+	var r CountdownRules
+
+	if err := m.DoContext(ctx, d, &r); err != nil {
+		return nil, err
+	}
+
+	return r.GetRules(), nil
+}
+
+func (m *DeviceManager) DeleteCountdownRules(d *devices.Device) error {
+	return m.DeleteCountdownRulesContext(context.Background(), d)
+}
+
+func (m *DeviceManager) DeleteCountdownRulesContext(ctx context.Context, d *devices.Device) error {
+	var r CountdownDeleteRules
+
+	return m.DoContext(ctx, d, &r)
+}
+
+func (m *DeviceManager) EditCountdownRule(d *devices.Device, rule CountdownRule) error {
+	return m.EditCountdownRuleContext(context.Background(), d, rule)
+}
+
+func (m *DeviceManager) EditCountdownRuleContext(ctx context.Context, d *devices.Device, rule CountdownRule) error {
+	if rule.Id == "" {
+		return fmt.Errorf("%w: rule has no id", ErrInvalidParameter)
+	}
+	if err := validateCountdown(&rule); err != nil {
+		return err
+	}
+
+	var r CountdownEditRule
+	r.SetRule(rule)
+
+	return m.DoContext(ctx, d, &r)
+}
+
+func (m *DeviceManager) StartCountdown(d *devices.Device, delay time.Duration, on bool) (string, error) {
+	return m.StartCountdownContext(context.Background(), d, delay, on)
+}
+
+// StartCountdownContext replaces any countdown on the device with one that
+// switches the relay on or off after delay. The countdown is run by the
+// device and survives restarts of the controller.
+func (m *DeviceManager) StartCountdownContext(ctx context.Context, d *devices.Device, delay time.Duration, on bool) (string, error) {
+	rule := CountdownRule{
+		Name:   "countdown",
+		Enable: 1,
+		Delay:  int(delay / time.Second),
+	}
+	if on {
+		rule.Action = 1
+	}
+
+	if err := validateCountdown(&rule); err != nil {
+		return "", err
+	}
+	if err := m.DeleteCountdownRulesContext(ctx, d); err != nil {
+		return "", err
+	}
+
+	return m.AddCountdownRuleContext(ctx, d, rule)
+}
+
+func validateCountdown(rule *CountdownRule) error {
+	if rule.Delay <= 0 {
+		return fmt.Errorf("%w: countdown delay %ds", ErrInvalidParameter, rule.Delay)
+	}
+	if rule.Action != 0 && rule.Action != 1 {
+		return fmt.Errorf("%w: countdown action %d", ErrInvalidParameter, rule.Action)
+	}
+	return nil
+}
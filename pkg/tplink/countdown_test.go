@@ -0,0 +1,97 @@
+package tplink
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+func countdownModules() mockModules {
+	var rules []map[string]interface{}
+
+	modules := plugModules()
+	modules["count_down"] = map[string]mockMethod{
+		"get_rules": func(map[string]interface{}) interface{} {
+			return map[string]interface{}{"err_code": 0, "rule_list": rules}
+		},
+		"add_rule": func(params map[string]interface{}) interface{} {
+			if len(rules) > 0 {
+				return map[string]interface{}{"err_code": -10, "err_msg": "table is full"}
+			}
+			params["id"] = "COUNTDOWN01"
+			params["remain"] = params["delay"]
+			rules = append(rules, params)
+			return map[string]interface{}{"err_code": 0, "id": params["id"]}
+		},
+		"edit_rule": func(params map[string]interface{}) interface{} {
+			if len(rules) == 0 || rules[0]["id"] != params["id"] {
+				return map[string]interface{}{"err_code": -14, "err_msg": "entry not exist"}
+			}
+			params["remain"] = params["delay"]
+			rules[0] = params
+			return map[string]interface{}{"err_code": 0}
+		},
+		"delete_all_rules": func(map[string]interface{}) interface{} {
+			rules = nil
+			return map[string]interface{}{"err_code": 0}
+		},
+	}
+	return modules
+}
+
+func TestCountdown(t *testing.T) {
+	api, transport := newMockManager(countdownModules().handler)
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	id, err := api.StartCountdown(device, 45*time.Minute, false)
+	if err != nil {
+		t.Fatalf("failed to start countdown: %s", err)
+	}
+
+	requests := transport.Requests()
+	expected := "{\"count_down\":{\"add_rule\":{\"name\":\"countdown\",\"enable\":1,\"delay\":2700,\"act\":0}}}"
+	if len(requests) != 2 || requests[1] != expected {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+
+	// A second countdown replaces the first one.
+	if id, err = api.StartCountdown(device, time.Minute, true); err != nil {
+		t.Fatalf("failed to restart countdown: %s", err)
+	}
+
+	rules, err := api.CountdownRules(device)
+	if err != nil {
+		t.Fatalf("failed to get countdown rules: %s", err)
+	}
+	if len(rules) != 1 || rules[0].Id != id || rules[0].Delay != 60 || rules[0].Action != 1 || rules[0].Remaining != 60 {
+		t.Fatalf("unexpected countdown rules %+v", rules)
+	}
+
+	rules[0].Delay = 120
+	if err = api.EditCountdownRule(device, rules[0]); err != nil {
+		t.Fatalf("failed to edit countdown rule: %s", err)
+	}
+	if err = api.DeleteCountdownRules(device); err != nil {
+		t.Fatalf("failed to delete countdown rules: %s", err)
+	}
+
+	if _, err = api.StartCountdown(device, 0, true); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCountdownUnsupported(t *testing.T) {
+	api, _ := newMockManager(plugHandler)
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	_, err := api.CountdownRules(device)
+	if !errors.Is(err, ErrUnsupportedFeature) || !IsModuleNotSupported(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -29,6 +29,14 @@ func DumpDeviceInfo(device *devices.Device, info *SystemInfo) {
 		fmt.Println("\tState: Off")
 	}
 
+	for _, child := range device.Children() {
+		state := "Off"
+		if child.RelayState() {
+			state = "On"
+		}
+		fmt.Printf("\tOutlet %s: %s (%s)\n", child.ChildId(), child.Alias(), state)
+	}
+
 	fmt.Println("Version Information")
 	fmt.Printf("\tSoftware Version: %s\n", device.SoftwareVersion())
 	fmt.Printf("\tHardware Version: %s\n", device.HardwareVersion())
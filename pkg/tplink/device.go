@@ -1,6 +1,8 @@
 package tplink
 
 import (
+	"strings"
+
 	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
 )
 
@@ -13,5 +15,24 @@ func BulbConfig(address string, options ...devices.DeviceConfigOption) devices.D
 func PlugConfig(address string, options ...devices.DeviceConfigOption) devices.DeviceConfig {
 	return devices.NewDeviceConfig(address,
 		append(options,
-			devices.WithDeviceType(devices.BulbDevice))...)
+			devices.WithDeviceType(devices.PlugDevice))...)
+}
+
+// DeviceType maps the type reported by the device onto a devices.DeviceType.
+// Plugs and switches report it as 'type', bulbs and strips as 'mic_type'.
+func (s *SystemInfo) DeviceType() devices.DeviceType {
+	kind := s.Type
+	if kind == "" {
+		kind = s.MicType
+	}
+
+	kind = strings.ToUpper(kind)
+	switch {
+	case strings.Contains(kind, "BULB"):
+		return devices.BulbDevice
+	case strings.Contains(kind, "PLUG"), strings.Contains(kind, "SWITCH"):
+		return devices.PlugDevice
+	}
+
+	return devices.UnknownDevice
 }
@@ -0,0 +1,230 @@
+package tplink
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// DimmerAction is what a dimmer does when its button is double clicked.
+type DimmerAction string
+
+const (
+	DimmerActionNone    DimmerAction = "none"
+	DimmerActionInstant DimmerAction = "instant_on_off"
+	DimmerActionGentle  DimmerAction = "gentle_on_off"
+	DimmerActionPreset  DimmerAction = "customize_preset"
+)
+
+// dimmerPresets is the number of brightness presets of a dimmer.
+const dimmerPresets = 4
+
+func (p DimmerParameters) FadeOn() time.Duration {
+	return time.Duration(p.FadeOnTime) * time.Millisecond
+}
+
+func (p DimmerParameters) FadeOff() time.Duration {
+	return time.Duration(p.FadeOffTime) * time.Millisecond
+}
+
+func (p DimmerParameters) GentleOn() time.Duration {
+	return time.Duration(p.GentleOnTime) * time.Millisecond
+}
+
+func (p DimmerParameters) GentleOff() time.Duration {
+	return time.Duration(p.GentleOffTime) * time.Millisecond
+}
+
+// Dimmer controls the dimmer module of wall dimmers such as the HS220. The
+// relay is still switched with SetRelayState, the dimmer sets the
+// brightness and how the light fades when it is switched.
+type Dimmer struct {
+	device devices.Addressable
+	mgr    *DeviceManager
+}
+
+func (m *DeviceManager) Dimmer(d *devices.Device) (*Dimmer, error) {
+	return m.DimmerContext(context.Background(), d)
+}
+
+func (m *DeviceManager) DimmerContext(ctx context.Context, d *devices.Device) (*Dimmer, error) {
+	info, err := m.SystemInfoContext(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.DeviceType() != devices.PlugDevice || info.Brightness == nil {
+		return nil, ErrUnsupportedFeature
+	}
+
+	return &Dimmer{device: d, mgr: m}, nil
+}
+
+func (d *Dimmer) Brightness() (int, error) {
+	return d.BrightnessContext(context.Background())
+}
+
+// BrightnessContext returns the brightness as a percentage, it is kept
+// while the relay is off.
+func (d *Dimmer) BrightnessContext(ctx context.Context) (int, error) {
+	info, err := d.mgr.SystemInfoContext(ctx, d.device)
+	if err != nil {
+		return 0, err
+	}
+	if info.Brightness == nil {
+		return 0, ErrUnsupportedFeature
+	}
+
+	return *info.Brightness, nil
+}
+
+func (d *Dimmer) Parameters() (*DimmerParameters, error) {
+	return d.ParametersContext(context.Background())
+}
+
+func (d *Dimmer) ParametersContext(ctx context.Context) (*DimmerParameters, error) {
+	var r DimmerGetParameters
+
+	if err := d.mgr.DoContext(ctx, d.device, &r); err != nil {
+		return nil, err
+	}
+
+	params := r.GetParameters()
+	return &params, nil
+}
+
+func (d *Dimmer) SetBrightness(brightness int) error {
+	return d.SetBrightnessContext(context.Background(), brightness)
+}
+
+// SetBrightnessContext sets the brightness between 1 and 100 percent. It does
+// not switch the relay, use SetRelayState to turn the light off.
+func (d *Dimmer) SetBrightnessContext(ctx context.Context, brightness int) error {
+	if brightness < 1 || brightness > 100 {
+		return fmt.Errorf("%w: brightness %d", ErrInvalidParameter, brightness)
+	}
+
+	var r DimmerBrightness
+	r.SetBrightness(brightness)
+
+	return d.mgr.DoContext(ctx, d.device, &r)
+}
+
+func (d *Dimmer) SetDoubleClickAction(action DimmerAction) error {
+	return d.SetDoubleClickActionContext(context.Background(), action)
+}
+
+func (d *Dimmer) SetDoubleClickActionContext(ctx context.Context, action DimmerAction) error {
+	switch action {
+	case DimmerActionNone, DimmerActionInstant, DimmerActionGentle:
+	case DimmerActionPreset:
+		return fmt.Errorf("%w: use SetDoubleClickPreset for presets", ErrInvalidParameter)
+	default:
+		return fmt.Errorf("%w: double click action '%s'", ErrInvalidParameter, action)
+	}
+
+	var r DimmerDoubleClickAction
+	r.SetAction(string(action))
+
+	return d.mgr.DoContext(ctx, d.device, &r)
+}
+
+func (d *Dimmer) SetDoubleClickPreset(index int) error {
+	return d.SetDoubleClickPresetContext(context.Background(), index)
+}
+
+// SetDoubleClickPresetContext makes a double click set the brightness of
+// the preset at index.
+func (d *Dimmer) SetDoubleClickPresetContext(ctx context.Context, index int) error {
+	if index < 0 || index >= dimmerPresets {
+		return fmt.Errorf("%w: preset %d", ErrInvalidParameter, index)
+	}
+
+	var r DimmerDoubleClickAction
+	r.SetPreset(string(DimmerActionPreset), index)
+
+	return d.mgr.DoContext(ctx, d.device, &r)
+}
+
+func (d *Dimmer) SetFadeOffTime(fade time.Duration) error {
+	return d.SetFadeOffTimeContext(context.Background(), fade)
+}
+
+// SetFadeOffTimeContext sets how long the light fades out when it is turned
+// off with the button.
+func (d *Dimmer) SetFadeOffTimeContext(ctx context.Context, fade time.Duration) error {
+	ms, err := dimmerTime("fade off time", fade)
+	if err != nil {
+		return err
+	}
+
+	var r DimmerFadeOffTime
+	r.SetFadeTime(ms)
+
+	return d.mgr.DoContext(ctx, d.device, &r)
+}
+
+func (d *Dimmer) SetFadeOnTime(fade time.Duration) error {
+	return d.SetFadeOnTimeContext(context.Background(), fade)
+}
+
+// SetFadeOnTimeContext sets how long the light fades in when it is turned on
+// with the button.
+func (d *Dimmer) SetFadeOnTimeContext(ctx context.Context, fade time.Duration) error {
+	ms, err := dimmerTime("fade on time", fade)
+	if err != nil {
+		return err
+	}
+
+	var r DimmerFadeOnTime
+	r.SetFadeTime(ms)
+
+	return d.mgr.DoContext(ctx, d.device, &r)
+}
+
+func (d *Dimmer) SetGentleOffTime(duration time.Duration) error {
+	return d.SetGentleOffTimeContext(context.Background(), duration)
+}
+
+// SetGentleOffTimeContext sets how long the light fades out for the gentle
+// off action.
+func (d *Dimmer) SetGentleOffTimeContext(ctx context.Context, duration time.Duration) error {
+	ms, err := dimmerTime("gentle off time", duration)
+	if err != nil {
+		return err
+	}
+
+	var r DimmerGentleOffTime
+	r.SetDuration(ms)
+
+	return d.mgr.DoContext(ctx, d.device, &r)
+}
+
+func (d *Dimmer) SetGentleOnTime(duration time.Duration) error {
+	return d.SetGentleOnTimeContext(context.Background(), duration)
+}
+
+// SetGentleOnTimeContext sets how long the light fades in for the gentle on
+// action.
+func (d *Dimmer) SetGentleOnTimeContext(ctx context.Context, duration time.Duration) error {
+	ms, err := dimmerTime("gentle on time", duration)
+	if err != nil {
+		return err
+	}
+
+	var r DimmerGentleOnTime
+	r.SetDuration(ms)
+
+	return d.mgr.DoContext(ctx, d.device, &r)
+}
+
+// dimmerTime converts a fade time to the milliseconds expected by the
+// device.
+func dimmerTime(name string, d time.Duration) (int, error) {
+	if d < 0 || d%time.Millisecond != 0 {
+		return 0, fmt.Errorf("%w: %s %s", ErrInvalidParameter, name, d)
+	}
+	return int(d.Milliseconds()), nil
+}
@@ -0,0 +1,152 @@
+package tplink
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// dimmerModules emulates a HS220. Settings are kept so they are reflected in
+// get_dimmer_parameters and the sysinfo.
+func dimmerModules() mockModules {
+	brightness := 50
+	params := map[string]interface{}{
+		"minThreshold":  11,
+		"fadeOnTime":    1000,
+		"fadeOffTime":   1000,
+		"gentleOnTime":  3000,
+		"gentleOffTime": 510000,
+		"rampRate":      30,
+		"bulb_type":     1,
+	}
+
+	set := func(key string, param string) mockMethod {
+		return func(p map[string]interface{}) interface{} {
+			params[key] = p[param]
+			return map[string]interface{}{"err_code": 0}
+		}
+	}
+
+	modules := plugModules()
+	modules["system"]["get_sysinfo"] = func(map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"err_code":   0,
+			"alias":      "Dining Room",
+			"brightness": brightness,
+			"mic_type":   "IOT.SMARTPLUGSWITCH",
+			"model":      "HS220(US)",
+		}
+	}
+	modules[dimmerModule] = map[string]mockMethod{
+		"get_dimmer_parameters": func(map[string]interface{}) interface{} {
+			out := map[string]interface{}{"err_code": 0}
+			for k, v := range params {
+				out[k] = v
+			}
+			return out
+		},
+		"set_brightness": func(p map[string]interface{}) interface{} {
+			brightness = int(p["brightness"].(float64))
+			return map[string]interface{}{"err_code": 0}
+		},
+		"set_double_click_action": mockSuccess,
+		"set_fade_off_time":       set("fadeOffTime", "fadeTime"),
+		"set_fade_on_time":        set("fadeOnTime", "fadeTime"),
+		"set_gentle_off_time":     set("gentleOffTime", "duration"),
+		"set_gentle_on_time":      set("gentleOnTime", "duration"),
+	}
+	return modules
+}
+
+func TestDimmer(t *testing.T) {
+	api, transport := newMockManager(dimmerModules().handler)
+
+	config := PlugConfig("mock")
+	dimmer, err := api.Dimmer(devices.NewDevice(&config))
+	if err != nil {
+		t.Fatalf("failed to get dimmer: %s", err)
+	}
+
+	if err = dimmer.SetBrightness(30); err != nil {
+		t.Fatalf("failed to set brightness: %s", err)
+	}
+	if brightness, err := dimmer.Brightness(); err != nil || brightness != 30 {
+		t.Fatalf("unexpected brightness %d: %v", brightness, err)
+	}
+
+	if err = dimmer.SetFadeOnTime(2 * time.Second); err != nil {
+		t.Fatalf("failed to set fade on time: %s", err)
+	}
+	if err = dimmer.SetFadeOffTime(500 * time.Millisecond); err != nil {
+		t.Fatalf("failed to set fade off time: %s", err)
+	}
+	if err = dimmer.SetGentleOnTime(10 * time.Second); err != nil {
+		t.Fatalf("failed to set gentle on time: %s", err)
+	}
+	if err = dimmer.SetGentleOffTime(time.Minute); err != nil {
+		t.Fatalf("failed to set gentle off time: %s", err)
+	}
+
+	params, err := dimmer.Parameters()
+	if err != nil {
+		t.Fatalf("failed to get dimmer parameters: %s", err)
+	}
+	if params.FadeOn() != 2*time.Second || params.FadeOff() != 500*time.Millisecond ||
+		params.GentleOn() != 10*time.Second || params.GentleOff() != time.Minute || params.MinThreshold != 11 {
+		t.Fatalf("unexpected dimmer parameters %+v", params)
+	}
+
+	if err = dimmer.SetDoubleClickAction(DimmerActionGentle); err != nil {
+		t.Fatalf("failed to set double click action: %s", err)
+	}
+	if err = dimmer.SetDoubleClickPreset(2); err != nil {
+		t.Fatalf("failed to set double click preset: %s", err)
+	}
+
+	requests := transport.Requests()
+	expected := []string{
+		`{"smartlife.iot.dimmer":{"set_double_click_action":{"mode":"gentle_on_off"}}}`,
+		`{"smartlife.iot.dimmer":{"set_double_click_action":{"mode":"customize_preset","index":2}}}`,
+	}
+	for i, req := range requests[len(requests)-2:] {
+		if req != expected[i] {
+			t.Fatalf("unexpected request %s", req)
+		}
+	}
+}
+
+func TestDimmerValidation(t *testing.T) {
+	api, transport := newMockManager(dimmerModules().handler)
+
+	config := PlugConfig("mock")
+	dimmer, err := api.Dimmer(devices.NewDevice(&config))
+	if err != nil {
+		t.Fatalf("failed to get dimmer: %s", err)
+	}
+	sent := len(transport.Requests())
+
+	invalid := []error{
+		dimmer.SetBrightness(0),
+		dimmer.SetBrightness(101),
+		dimmer.SetFadeOnTime(-time.Second),
+		dimmer.SetGentleOnTime(time.Microsecond),
+		dimmer.SetDoubleClickAction(DimmerActionPreset),
+		dimmer.SetDoubleClickAction("toggle"),
+		dimmer.SetDoubleClickPreset(dimmerPresets),
+	}
+	for i, err := range invalid {
+		if !errors.Is(err, ErrInvalidParameter) {
+			t.Fatalf("unexpected error %d: %v", i, err)
+		}
+	}
+	if len(transport.Requests()) != sent {
+		t.Fatalf("invalid requests were sent to the device")
+	}
+
+	api, _ = newMockManager(plugHandler)
+	if _, err = api.Dimmer(devices.NewDevice(&config)); !errors.Is(err, ErrUnsupportedFeature) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
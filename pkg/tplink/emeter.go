@@ -1,11 +1,24 @@
 package tplink
 
 import (
-    "encoding/json"
+    "context"
+    "fmt"
+    "time"
 
     "github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
 )
 
+// EnergyUsage is the energy used in kWh on a day, or in a month when Day is
+// zero. Reported is false for periods the device has no statistics for,
+// such as days it was unplugged.
+type EnergyUsage struct {
+    Year     int
+    Month    int
+    Day      int
+    Energy   float32
+    Reported bool
+}
+
 type EMeter struct {
     device devices.Addressable
     mgr    *DeviceManager
@@ -16,16 +29,143 @@ func newElectricityMeter(d devices.Addressable, mgr *DeviceManager) *EMeter {
 }
 
 func (e *EMeter) Realtime() (*RealTimeEnergy, error) {
+    return e.RealtimeContext(context.Background())
+}
+
+func (e *EMeter) RealtimeContext(ctx context.Context) (*RealTimeEnergy, error) {
     var emeterInfo ElectricityMeterInfo
 
-    res, err := e.mgr.Marshal(e.device, emeterInfo)
-    if err != nil {
+    if err := e.mgr.DoContext(ctx, e.device, &emeterInfo); err != nil {
         return nil, err
     }
 
-    if err = json.Unmarshal(res, &emeterInfo); err != nil {
+    return emeterInfo.Realtime(), nil
+}
+
+func (e *EMeter) DailyStats(year int, month int) ([]EnergyUsage, error) {
+    return e.DailyStatsContext(context.Background(), year, month)
+}
+
+// DailyStatsContext returns one entry per day of the month, sorted by day.
+// Days in the future are left out unless the device reported them.
+func (e *EMeter) DailyStatsContext(ctx context.Context, year int, month int) ([]EnergyUsage, error) {
+    if year <= 0 || month < 1 || month > 12 {
+        return nil, fmt.Errorf("%w: month %d-%02d", ErrInvalidParameter, year, month)
+    }
+
+    var stats EMeterDailyStats
+    stats.SetMonth(year, month)
+
+    if err := e.mgr.DoContext(ctx, e.device, &stats); err != nil {
         return nil, err
     }
 
-    return emeterInfo.Realtime(), nil
+    days := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day()
+
+    // Days after today on the host are left out, unless the device already
+    // reported them because its clock is ahead of the host.
+    length := 0
+    now := time.Now()
+    for day := 1; day <= days; day++ {
+        if time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local).After(now) {
+            break
+        }
+        length = day
+    }
+
+    dayList := stats.DailyStats().DayList
+    for _, stat := range dayList {
+        if stat.Year == year && stat.Month == month && stat.Day > length && stat.Day <= days {
+            length = stat.Day
+        }
+    }
+
+    series := make([]EnergyUsage, length)
+    for i := range series {
+        series[i] = EnergyUsage{Year: year, Month: month, Day: i + 1}
+    }
+
+    for _, stat := range dayList {
+        if stat.Year != year || stat.Month != month || stat.Day < 1 || stat.Day > length {
+            continue
+        }
+        series[stat.Day-1].Energy = stat.Energy
+        series[stat.Day-1].Reported = true
+    }
+
+    return series, nil
+}
+
+func (e *EMeter) EraseStats() error {
+    return e.EraseStatsContext(context.Background())
+}
+
+// EraseStatsContext deletes all daily and monthly statistics on the device.
+func (e *EMeter) EraseStatsContext(ctx context.Context) error {
+    var erase EMeterEraseStats
+
+    return e.mgr.DoContext(ctx, e.device, &erase)
+}
+
+func (e *EMeter) MonthlyStats(year int) ([]EnergyUsage, error) {
+    return e.MonthlyStatsContext(context.Background(), year)
+}
+
+// MonthlyStatsContext returns one entry per month of the year, sorted by
+// month. Months in the future are left out unless the device reported them.
+func (e *EMeter) MonthlyStatsContext(ctx context.Context, year int) ([]EnergyUsage, error) {
+    if year <= 0 {
+        return nil, fmt.Errorf("%w: year %d", ErrInvalidParameter, year)
+    }
+
+    var stats EMeterMonthlyStats
+    stats.SetYear(year)
+
+    if err := e.mgr.DoContext(ctx, e.device, &stats); err != nil {
+        return nil, err
+    }
+
+    // Months after the current one on the host are left out, unless the
+    // device already reported them because its clock is ahead of the host.
+    length := 0
+    now := time.Now()
+    for month := 1; month <= 12; month++ {
+        if time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local).After(now) {
+            break
+        }
+        length = month
+    }
+
+    monthList := stats.MonthlyStats().MonthList
+    for _, stat := range monthList {
+        if stat.Year == year && stat.Month > length && stat.Month <= 12 {
+            length = stat.Month
+        }
+    }
+
+    series := make([]EnergyUsage, length)
+    for i := range series {
+        series[i] = EnergyUsage{Year: year, Month: i + 1}
+    }
+
+    for _, stat := range monthList {
+        if stat.Year != year || stat.Month < 1 || stat.Month > length {
+            continue
+        }
+        series[stat.Month-1].Energy = stat.Energy
+        series[stat.Month-1].Reported = true
+    }
+
+    return series, nil
+}
+
+// MissingUsage returns the entries of the series the device did not report.
+func MissingUsage(series []EnergyUsage) []EnergyUsage {
+    var missing []EnergyUsage
+    for _, usage := range series {
+        if !usage.Reported {
+            missing = append(missing, usage)
+        }
+    }
+    return missing
 }
@@ -0,0 +1,169 @@
+package tplink
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+func emeterModules() mockModules {
+	erased := false
+
+	modules := plugModules()
+	modules["emeter"]["get_daystat"] = func(params map[string]interface{}) interface{} {
+		if erased || params["year"] != 2021.0 || params["month"] != 11.0 {
+			return map[string]interface{}{"err_code": 0, "day_list": []interface{}{}}
+		}
+		// Unsorted and without the 2nd and 3rd day.
+		return map[string]interface{}{
+			"err_code": 0,
+			"day_list": []map[string]interface{}{
+				{"year": 2021, "month": 11, "day": 14, "energy": 0.9},
+				{"year": 2021, "month": 11, "day": 1, "energy": 1.172},
+				{"year": 2021, "month": 11, "day": 4, "energy": 1.148},
+			},
+		}
+	}
+	modules["emeter"]["get_monthstat"] = func(params map[string]interface{}) interface{} {
+		if params["year"] != 2021.0 {
+			return map[string]interface{}{"err_code": -3, "err_msg": "invalid argument"}
+		}
+		return map[string]interface{}{
+			"err_code": 0,
+			"month_list": []map[string]interface{}{
+				{"year": 2021, "month": 11, "energy": 15.867},
+				{"year": 2021, "month": 2, "energy": 168.62},
+			},
+		}
+	}
+	modules["emeter"]["erase_emeter_stat"] = func(map[string]interface{}) interface{} {
+		erased = true
+		return map[string]interface{}{"err_code": 0}
+	}
+	return modules
+}
+
+func TestEMeterDailyStats(t *testing.T) {
+	api, transport := newMockManager(emeterModules().handler)
+
+	config := PlugConfig("mock")
+	meter, err := api.ElectricityMeter(devices.NewDevice(&config, devices.WithFeatures([]string{"ENE"})))
+	if err != nil {
+		t.Fatalf("failed to access emeter: %s", err)
+	}
+
+	days, err := meter.DailyStats(2021, 11)
+	if err != nil {
+		t.Fatalf("failed to get daily stats: %s", err)
+	}
+
+	requests := transport.Requests()
+	if requests[0] != "{\"emeter\":{\"get_daystat\":{\"month\":11,\"year\":2021}}}" {
+		t.Fatalf("unexpected request '%s'", requests[0])
+	}
+
+	if len(days) != 30 {
+		t.Fatalf("unexpected number of days %d", len(days))
+	}
+	for i, day := range days {
+		if day.Day != i+1 || day.Month != 11 || day.Year != 2021 {
+			t.Fatalf("unexpected day %+v at %d", day, i)
+		}
+	}
+	if !days[0].Reported || days[0].Energy != 1.172 || !days[13].Reported || days[13].Energy != 0.9 {
+		t.Fatalf("unexpected usage %+v %+v", days[0], days[13])
+	}
+
+	missing := MissingUsage(days)
+	if len(missing) != 27 || missing[0].Day != 2 || missing[1].Day != 3 || missing[2].Day != 5 {
+		t.Fatalf("unexpected missing days %+v", missing)
+	}
+
+	if _, err = meter.DailyStats(2021, 13); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = meter.EraseStats(); err != nil {
+		t.Fatalf("failed to erase stats: %s", err)
+	}
+	if days, err = meter.DailyStats(2021, 11); err != nil || len(MissingUsage(days)) != 30 {
+		t.Fatalf("stats remain after erase: %v", err)
+	}
+}
+
+func TestEMeterMonthlyStats(t *testing.T) {
+	api, _ := newMockManager(emeterModules().handler)
+
+	config := PlugConfig("mock")
+	meter, err := api.ElectricityMeter(devices.NewDevice(&config, devices.WithFeatures([]string{"ENE"})))
+	if err != nil {
+		t.Fatalf("failed to access emeter: %s", err)
+	}
+
+	months, err := meter.MonthlyStats(2021)
+	if err != nil {
+		t.Fatalf("failed to get monthly stats: %s", err)
+	}
+	if len(months) != 12 || months[1].Energy != 168.62 || months[10].Energy != 15.867 {
+		t.Fatalf("unexpected months %+v", months)
+	}
+	if months[0].Reported || !months[1].Reported || len(MissingUsage(months)) != 10 {
+		t.Fatalf("unexpected missing months %+v", MissingUsage(months))
+	}
+
+	if _, err = meter.MonthlyStats(2020); !IsInvalidArgument(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestEMeterStatsAheadOfHost covers a device whose clock is ahead of the
+// host, its statistics for days and months the host considers future are
+// kept.
+func TestEMeterStatsAheadOfHost(t *testing.T) {
+	year := time.Now().Year() + 1
+
+	modules := plugModules()
+	modules["emeter"]["get_daystat"] = func(map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"err_code": 0,
+			"day_list": []map[string]interface{}{
+				{"year": year, "month": 1, "day": 3, "energy": 0.5},
+				{"year": year, "month": 1, "day": 1, "energy": 1.5},
+				{"year": year, "month": 1, "day": 32, "energy": 9.9},
+			},
+		}
+	}
+	modules["emeter"]["get_monthstat"] = func(map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"err_code": 0,
+			"month_list": []map[string]interface{}{
+				{"year": year, "month": 2, "energy": 20.0},
+			},
+		}
+	}
+	api, _ := newMockManager(modules.handler)
+
+	config := PlugConfig("mock")
+	meter, err := api.ElectricityMeter(devices.NewDevice(&config, devices.WithFeatures([]string{"ENE"})))
+	if err != nil {
+		t.Fatalf("failed to access emeter: %s", err)
+	}
+
+	days, err := meter.DailyStats(year, 1)
+	if err != nil {
+		t.Fatalf("failed to get daily stats: %s", err)
+	}
+	if len(days) != 3 || !days[0].Reported || days[1].Reported || days[2].Energy != 0.5 {
+		t.Fatalf("unexpected days %+v", days)
+	}
+
+	months, err := meter.MonthlyStats(year)
+	if err != nil {
+		t.Fatalf("failed to get monthly stats: %s", err)
+	}
+	if len(months) != 2 || months[0].Reported || months[1].Energy != 20.0 {
+		t.Fatalf("unexpected months %+v", months)
+	}
+}
@@ -3,7 +3,6 @@ package tplink
 import (
     "bytes"
     "encoding/binary"
-    "io"
 )
 
 const (
@@ -11,60 +10,73 @@ const (
 )
 
 func Decrypt(data []byte) (*bytes.Buffer, bool) {
-    key := EncryptionKey
-
     if len(data) < 5 {
         return nil, false
     }
 
-    buf := bytes.NewBuffer(data)
+    msgLen := binary.BigEndian.Uint32(data)
+    payload := data[4:]
 
-    var msgLen uint32
-    if err := binary.Read(buf, binary.BigEndian, &msgLen); err != nil {
-        return nil, false
+    // A length of zero is not trusted, the whole payload is decrypted.
+    if msgLen != 0 && uint32(len(payload)) > msgLen {
+        payload = payload[:msgLen]
     }
 
     msg := new(bytes.Buffer)
-    msg.Grow(int(msgLen))
-
-ForLoop:
-    for {
-        b, err := buf.ReadByte()
-        if err != nil {
-            switch err {
-            case io.EOF:
-                break ForLoop
-            default:
-                return nil, false
-            }
-        }
-
-        plain := key ^ b
-        key = b
-        msg.WriteByte(plain)
+    msg.Grow(len(payload))
+    decrypt(msg, payload)
 
-        if uint32(msg.Len()) == msgLen {
-            break
-        }
-    }
+    return msg, msg.Len() != 0
+}
+
+// DecryptDatagram decrypts a message received over UDP. Unlike the TCP
+// framing used by Decrypt, datagrams carry no length header.
+func DecryptDatagram(data []byte) (*bytes.Buffer, bool) {
+    msg := new(bytes.Buffer)
+    msg.Grow(len(data))
+    decrypt(msg, data)
 
     return msg, msg.Len() != 0
 }
 
 func Encrypt(in []byte) (*bytes.Buffer, bool) {
     buf := new(bytes.Buffer)
-    key := EncryptionKey
 
     if len(in) > 0 {
         buf.Grow(len(in) + 4)
         _ = binary.Write(buf, binary.BigEndian, uint32(len(in)))
-
-        for _, c := range in {
-            cipher := c ^ key
-            key = cipher
-            _ = binary.Write(buf, binary.BigEndian, cipher)
-        }
+        encrypt(buf, in)
     }
 
     return buf, buf.Len() != 0
 }
+
+// EncryptDatagram encrypts a message to be sent over UDP. The result has no
+// length header, see DecryptDatagram.
+func EncryptDatagram(in []byte) (*bytes.Buffer, bool) {
+    buf := new(bytes.Buffer)
+    buf.Grow(len(in))
+    encrypt(buf, in)
+
+    return buf, buf.Len() != 0
+}
+
+// decrypt reverses the autokey cipher shared by the TCP and UDP framing,
+// each cipher byte is the key of the next.
+func decrypt(dst *bytes.Buffer, data []byte) {
+    key := EncryptionKey
+
+    for _, b := range data {
+        dst.WriteByte(key ^ b)
+        key = b
+    }
+}
+
+func encrypt(dst *bytes.Buffer, in []byte) {
+    key := EncryptionKey
+
+    for _, c := range in {
+        key ^= c
+        dst.WriteByte(key)
+    }
+}
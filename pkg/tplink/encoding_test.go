@@ -66,3 +66,19 @@ func TestTpLinkDecrypt(t *testing.T) {
 		CompareDecrypted(t, msg, output.Bytes())
 	}
 }
+
+func TestTpLinkDatagram(t *testing.T) {
+	for msg, expected := range testData {
+		buf, ok := EncryptDatagram([]byte(msg))
+		if !ok {
+			t.Fatalf("Encrypting datagram '%s' failed", msg)
+		}
+		CompareEncrypted(t, msg, expected[4:], buf.Bytes())
+
+		output, ok := DecryptDatagram(buf.Bytes())
+		if !ok {
+			t.Fatalf("Decrypting datagram '%s' failed", msg)
+		}
+		CompareDecrypted(t, msg, output.Bytes())
+	}
+}
@@ -0,0 +1,150 @@
+package tplink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// Error codes reported by the device firmware in 'err_code'.
+const (
+	ErrCodeModuleNotSupported = -1
+	ErrCodeMethodNotSupported = -2
+	ErrCodeInvalidArgument    = -3
+)
+
+// ProtocolError is returned when the device answers a request with a
+// non-zero 'err_code'. It matches ErrProtocolOperationFailed with errors.Is,
+// and ErrUnsupportedFeature when the module or method is not implemented.
+type ProtocolError struct {
+	Address string
+	Module  string
+	Method  string
+	Code    int
+	Message string
+}
+
+func (e *ProtocolError) Error() string {
+	operation := e.Module
+	if e.Method != "" {
+		operation = fmt.Sprintf("%s.%s", e.Module, e.Method)
+	}
+
+	if e.Message == "" {
+		return fmt.Sprintf("%s on '%s' failed with error code %d",
+			operation, e.Address, e.Code)
+	}
+	return fmt.Sprintf("%s on '%s' failed with error code %d: %s",
+		operation, e.Address, e.Code, e.Message)
+}
+
+func (e *ProtocolError) Is(target error) bool {
+	switch target {
+	case ErrProtocolOperationFailed:
+		return true
+	case ErrUnsupportedFeature:
+		return e.Code == ErrCodeModuleNotSupported || e.Code == ErrCodeMethodNotSupported
+	}
+	return false
+}
+
+func hasErrorCode(err error, code int) bool {
+	var protocolErr *ProtocolError
+	if errors.As(err, &protocolErr) {
+		return protocolErr.Code == code
+	}
+	return false
+}
+
+// IsModuleNotSupported reports whether the device does not implement the
+// module the request was addressed to.
+func IsModuleNotSupported(err error) bool {
+	return hasErrorCode(err, ErrCodeModuleNotSupported)
+}
+
+// IsMethodNotSupported reports whether the module exists on the device but
+// does not implement the requested method.
+func IsMethodNotSupported(err error) bool {
+	return hasErrorCode(err, ErrCodeMethodNotSupported)
+}
+
+// IsInvalidArgument reports whether the device rejected the parameters of
+// the request.
+func IsInvalidArgument(err error) bool {
+	return hasErrorCode(err, ErrCodeInvalidArgument)
+}
+
+// IsUnsupported reports whether the module or the method is not available
+// on the device. Callers use it to degrade gracefully across models.
+func IsUnsupported(err error) bool {
+	return IsModuleNotSupported(err) || IsMethodNotSupported(err)
+}
+
+func protocolError(d devices.Addressable, module string, method string, status errorCode) error {
+	if status.ErrorCode == 0 {
+		return nil
+	}
+
+	return &ProtocolError{
+		Address: deviceAddress(d),
+		Module:  module,
+		Method:  method,
+		Code:    status.ErrorCode,
+		Message: status.ErrorMessage,
+	}
+}
+
+// decodeResponse locates module.method in the response and checks both the
+// module and the method level 'err_code'. Unknown modules are reported by
+// the firmware at the module level. The method's result is returned.
+func decodeResponse(d devices.Addressable, res []byte, module string, method string) (json.RawMessage, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(res, &doc); err != nil {
+		return nil, err
+	}
+
+	raw, ok := doc[module]
+	if !ok {
+		return nil, protocolError(d, module, method, errorCode{
+			ErrorCode:    ErrCodeModuleNotSupported,
+			ErrorMessage: "module missing from response",
+		})
+	}
+
+	return decodeModule(d, raw, module, method)
+}
+
+func decodeModule(d devices.Addressable, raw json.RawMessage, module string, method string) (json.RawMessage, error) {
+	var methods map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &methods); err != nil {
+		return nil, err
+	}
+
+	var status errorCode
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return nil, err
+	}
+	if err := protocolError(d, module, method, status); err != nil {
+		return nil, err
+	}
+
+	result, ok := methods[method]
+	if !ok {
+		return nil, protocolError(d, module, method, errorCode{
+			ErrorCode:    ErrCodeMethodNotSupported,
+			ErrorMessage: "method missing from response",
+		})
+	}
+
+	status = errorCode{}
+	if err := json.Unmarshal(result, &status); err != nil {
+		return nil, err
+	}
+	if err := protocolError(d, module, method, status); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
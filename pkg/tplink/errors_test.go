@@ -0,0 +1,83 @@
+package tplink
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+func TestProtocolErrorMethodNotSupported(t *testing.T) {
+	modules := plugModules()
+	delete(modules["system"], "reboot")
+
+	api, _ := newMockManager(modules.handler)
+
+	config := PlugConfig("10.0.0.1")
+	device := devices.NewDevice(&config)
+
+	err := api.Reboot(device, 1)
+	if !errors.Is(err, ErrProtocolOperationFailed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !IsMethodNotSupported(err) || IsModuleNotSupported(err) {
+		t.Fatalf("unexpected error classification: %v", err)
+	}
+
+	var protocolErr *ProtocolError
+	if !errors.As(err, &protocolErr) {
+		t.Fatalf("unexpected error type: %T", err)
+	}
+	if protocolErr.Address != "10.0.0.1:9999" ||
+		protocolErr.Module != "system" ||
+		protocolErr.Method != "reboot" ||
+		protocolErr.Code != ErrCodeMethodNotSupported ||
+		protocolErr.Message != "member not support" {
+		t.Fatalf("unexpected protocol error: %+v", protocolErr)
+	}
+}
+
+func TestProtocolErrorModuleNotSupported(t *testing.T) {
+	modules := plugModules()
+	delete(modules, "emeter")
+
+	api, _ := newMockManager(modules.handler)
+
+	config := PlugConfig("mock")
+	device, err := api.LoadDevice(&config)
+	if err != nil {
+		t.Fatalf("failed to load mock device: %s", err)
+	}
+
+	meter, err := api.ElectricityMeter(device)
+	if err != nil {
+		t.Fatalf("failed to access electricity meter: %s", err)
+	}
+
+	_, err = meter.Realtime()
+	if !IsModuleNotSupported(err) || !IsUnsupported(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !errors.Is(err, ErrProtocolOperationFailed) {
+		t.Fatalf("protocol error does not match sentinel: %v", err)
+	}
+}
+
+func TestProtocolErrorInvalidArgument(t *testing.T) {
+	modules := plugModules()
+	modules["system"]["set_dev_alias"] = func(map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"err_code": ErrCodeInvalidArgument,
+			"err_msg":  "invalid argument",
+		}
+	}
+
+	api, _ := newMockManager(modules.handler)
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	if err := api.SetAlias(device, ""); !IsInvalidArgument(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package tplink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+var ErrFirmwareUnchanged = errors.New("device did not report a new firmware version")
+var ErrFirmwareTimeout = errors.New("device did not finish the firmware upgrade in time")
+var ErrFirmwareDownloadFailed = errors.New("device failed to download the firmware")
+
+type FirmwareOption func(*FirmwareOptions)
+
+type FirmwareOptions struct {
+	// PollInterval is the time between two polls of the download state and,
+	// once flashing, of the system info.
+	PollInterval time.Duration
+	// Progress is called with every download state polled from the device.
+	Progress func(DownloadState)
+	// Timeout bounds the whole upgrade from download to the device coming
+	// back after the reboot.
+	Timeout time.Duration
+}
+
+func WithPollInterval(interval time.Duration) FirmwareOption {
+	return func(o *FirmwareOptions) {
+		o.PollInterval = interval
+	}
+}
+
+func WithProgress(progress func(DownloadState)) FirmwareOption {
+	return func(o *FirmwareOptions) {
+		o.Progress = progress
+	}
+}
+
+func WithUpgradeTimeout(timeout time.Duration) FirmwareOption {
+	return func(o *FirmwareOptions) {
+		o.Timeout = timeout
+	}
+}
+
+func DefaultFirmwareOptions() *FirmwareOptions {
+	return &FirmwareOptions{
+		PollInterval: 2 * time.Second,
+		Timeout:      10 * time.Minute,
+	}
+}
+
+// FirmwareUpgrade records an upgrade of a device.
+type FirmwareUpgrade struct {
+	Address  string
+	URL      string
+	Before   string
+	After    string
+	Started  time.Time
+	Finished time.Time
+}
+
+func (u *FirmwareUpgrade) Changed() bool {
+	return u.After != "" && u.After != u.Before
+}
+
+func (m *DeviceManager) DownloadFirmware(d *devices.Device, firmwareURL string) error {
+	return m.DownloadFirmwareContext(context.Background(), d, firmwareURL)
+}
+
+// DownloadFirmwareContext makes the device download the firmware from the
+// URL. The download runs in the background, see DownloadState.
+func (m *DeviceManager) DownloadFirmwareContext(ctx context.Context, d *devices.Device, firmwareURL string) error {
+	u, err := url.Parse(firmwareURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: firmware url '%s'", ErrInvalidParameter, firmwareURL)
+	}
+
+	var r SystemDownloadFirmware
+	r.SetURL(firmwareURL)
+
+	return m.DoContext(ctx, d, &r)
+}
+
+func (m *DeviceManager) DownloadState(d *devices.Device) (*DownloadState, error) {
+	return m.DownloadStateContext(context.Background(), d)
+}
+
+func (m *DeviceManager) DownloadStateContext(ctx context.Context, d *devices.Device) (*DownloadState, error) {
+	var r SystemDownloadState
+
+	if err := m.DoContext(ctx, d, &r); err != nil {
+		return nil, err
+	}
+
+	return r.DownloadState(), nil
+}
+
+func (m *DeviceManager) FlashFirmware(d *devices.Device) error {
+	return m.FlashFirmwareContext(context.Background(), d)
+}
+
+// FlashFirmwareContext flashes the downloaded firmware, the device reboots
+// once it is done.
+func (m *DeviceManager) FlashFirmwareContext(ctx context.Context, d *devices.Device) error {
+	var r SystemFlashFirmware
+
+	return m.DoContext(ctx, d, &r)
+}
+
+func (m *DeviceManager) UpgradeFirmware(d *devices.Device, firmwareURL string, opts ...FirmwareOption) (*FirmwareUpgrade, error) {
+	return m.UpgradeFirmwareContext(context.Background(), d, firmwareURL, opts...)
+}
+
+// UpgradeFirmwareContext downloads and flashes the firmware, then waits for
+// the device to come back. The returned FirmwareUpgrade holds the version
+// before and after the upgrade, and is returned along with
+// ErrFirmwareUnchanged when the version did not change. A download which
+// fails on the device ends the upgrade with ErrFirmwareDownloadFailed.
+func (m *DeviceManager) UpgradeFirmwareContext(ctx context.Context, d *devices.Device, firmwareURL string, opts ...FirmwareOption) (*FirmwareUpgrade, error) {
+	options := DefaultFirmwareOptions()
+	for _, option := range opts {
+		option(options)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+
+	info, err := m.SystemInfoContext(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	upgrade := &FirmwareUpgrade{
+		Address: deviceAddress(d),
+		URL:     firmwareURL,
+		Before:  info.SoftwareVersion,
+		Started: time.Now(),
+	}
+
+	if err = m.DownloadFirmwareContext(ctx, d, firmwareURL); err != nil {
+		return nil, err
+	}
+
+	var state *DownloadState
+	for {
+		if state, err = m.DownloadStateContext(ctx, d); err != nil {
+			return nil, upgradeError(ctx, err)
+		}
+		if options.Progress != nil {
+			options.Progress(*state)
+		}
+		if state.Failed() {
+			return nil, fmt.Errorf("%w: status %d at %d%%", ErrFirmwareDownloadFailed, state.Status, state.Ratio)
+		}
+		if state.Complete() {
+			break
+		}
+		if err = wait(ctx, options.PollInterval); err != nil {
+			return nil, upgradeError(ctx, err)
+		}
+	}
+
+	m.Logger().Info("flashing firmware",
+		zap.String("device", upgrade.Address),
+		zap.String("version", upgrade.Before))
+
+	if err = m.FlashFirmwareContext(ctx, d); err != nil {
+		return nil, err
+	}
+
+	// The device may still answer with the old version before it starts
+	// flashing. A reply is only final once the device went away or reported
+	// the update, or the version changed. A device which does neither within
+	// the flash and reboot time it announced kept its firmware.
+	deadline := time.Now().Add(time.Duration(state.FlashTime+state.RebootTime)*time.Second + options.PollInterval)
+	restarted := false
+
+	for {
+		if err = wait(ctx, options.PollInterval); err != nil {
+			return nil, upgradeError(ctx, err)
+		}
+
+		info, err = m.SystemInfoContext(ctx, d)
+		if err != nil || info.Updating != 0 {
+			restarted = true
+			continue
+		}
+		if restarted || info.SoftwareVersion != upgrade.Before || time.Now().After(deadline) {
+			break
+		}
+	}
+
+	upgrade.After = info.SoftwareVersion
+	upgrade.Finished = time.Now()
+
+	d.Apply(devices.WithSoftwareVersion(upgrade.After))
+
+	m.Logger().Info("firmware upgrade finished",
+		zap.String("device", upgrade.Address),
+		zap.String("before", upgrade.Before),
+		zap.String("after", upgrade.After))
+
+	if !upgrade.Changed() {
+		return upgrade, ErrFirmwareUnchanged
+	}
+	return upgrade, nil
+}
+
+func upgradeError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %s", ErrFirmwareTimeout, err)
+	}
+	return err
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
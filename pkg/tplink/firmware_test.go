@@ -0,0 +1,232 @@
+package tplink
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// firmwareDevice emulates the firmware upgrade of a plug. The firmware image
+// is fetched from the URL of the download request and its content becomes
+// the new software version. The device keeps answering with the old version
+// for linger requests after flashing, then it is unreachable for a few
+// requests while it reboots. A failing device reports a failed download.
+type firmwareDevice struct {
+	failing   bool
+	mutex     sync.Mutex
+	image     []byte
+	linger    int
+	lingering int
+	pending   string
+	polls     int
+	rebooting int
+	transport *mockTransport
+	version   string
+}
+
+func newFirmwareDevice(version string) *firmwareDevice {
+	device := &firmwareDevice{version: version}
+
+	modules := plugModules()
+	modules["system"]["get_sysinfo"] = func(map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"err_code": 0,
+			"model":    "HS110(US)",
+			"sw_ver":   device.version,
+		}
+	}
+	modules["system"]["download_firmware"] = func(params map[string]interface{}) interface{} {
+		url, _ := params["url"].(string)
+		res, err := http.Get(url)
+		if err != nil || res.StatusCode != http.StatusOK {
+			return map[string]interface{}{"err_code": -7, "err_msg": "download failed"}
+		}
+		defer res.Body.Close()
+
+		device.image, _ = io.ReadAll(res.Body)
+		return map[string]interface{}{"err_code": 0}
+	}
+	modules["system"]["get_download_state"] = func(map[string]interface{}) interface{} {
+		device.polls++
+		if device.failing {
+			return map[string]interface{}{"err_code": 0, "status": DownloadStatusFailed, "ratio": 20}
+		}
+		ratio := device.polls * 40
+		if ratio > 100 {
+			ratio = 100
+		}
+		return map[string]interface{}{
+			"err_code":    0,
+			"status":      1,
+			"ratio":       ratio,
+			"reboot_time": 10,
+			"flash_time":  30,
+		}
+	}
+	modules["system"]["flash_firmware"] = func(map[string]interface{}) interface{} {
+		if len(device.image) == 0 {
+			return map[string]interface{}{"err_code": -1, "err_msg": "no firmware"}
+		}
+		device.pending = string(device.image)
+		device.lingering = device.linger
+		return map[string]interface{}{"err_code": 0}
+	}
+
+	device.transport = newMockTransport(modules.handler)
+	return device
+}
+
+func (f *firmwareDevice) Send(ctx context.Context, d devices.Addressable, data []byte) ([]byte, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.pending != "" && f.lingering == 0 {
+		f.version, f.pending = f.pending, ""
+		f.rebooting = 3
+	}
+	if f.rebooting > 0 {
+		f.rebooting--
+		return nil, syscall.ECONNREFUSED
+	}
+
+	if f.pending != "" {
+		f.lingering--
+	}
+	return f.transport.Send(ctx, d, data)
+}
+
+func firmwareServer(image string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/firmware.bin", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, image)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestUpgradeFirmware(t *testing.T) {
+	server := firmwareServer("1.2.6 Build 200727 Rel.121701")
+	defer server.Close()
+
+	device := newFirmwareDevice("1.1.1 Build 160725 Rel.164033")
+	api := NewDeviceManager(devices.NewDeviceManager(),
+		WithTransport(device),
+		WithRetryPolicy(NoRetryPolicy()))
+
+	config := PlugConfig("mock")
+	d := devices.NewDevice(&config, devices.WithSoftwareVersion("1.1.1 Build 160725 Rel.164033"))
+	var progress []int
+
+	upgrade, err := api.UpgradeFirmware(d, server.URL+"/firmware.bin",
+		WithPollInterval(time.Millisecond),
+		WithProgress(func(state DownloadState) {
+			progress = append(progress, state.Ratio)
+		}))
+	if err != nil {
+		t.Fatalf("failed to upgrade firmware: %s", err)
+	}
+
+	if upgrade.Before != "1.1.1 Build 160725 Rel.164033" || upgrade.After != "1.2.6 Build 200727 Rel.121701" {
+		t.Fatalf("unexpected versions '%s' -> '%s'", upgrade.Before, upgrade.After)
+	}
+	if !upgrade.Changed() || upgrade.Finished.Before(upgrade.Started) {
+		t.Fatalf("unexpected upgrade %+v", upgrade)
+	}
+	if len(progress) != 3 || progress[0] != 40 || progress[2] != 100 {
+		t.Fatalf("unexpected progress %v", progress)
+	}
+	if d.SoftwareVersion() != upgrade.After {
+		t.Fatalf("cached version was not updated: '%s'", d.SoftwareVersion())
+	}
+}
+
+func TestUpgradeFirmwareDelayedReboot(t *testing.T) {
+	server := firmwareServer("1.2.6 Build 200727 Rel.121701")
+	defer server.Close()
+
+	device := newFirmwareDevice("1.1.1 Build 160725 Rel.164033")
+	device.linger = 1
+
+	api := NewDeviceManager(devices.NewDeviceManager(),
+		WithTransport(device),
+		WithRetryPolicy(NoRetryPolicy()))
+
+	config := PlugConfig("mock")
+	upgrade, err := api.UpgradeFirmware(devices.NewDevice(&config), server.URL+"/firmware.bin",
+		WithPollInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to upgrade firmware: %s", err)
+	}
+	if upgrade.After != "1.2.6 Build 200727 Rel.121701" {
+		t.Fatalf("unexpected version '%s'", upgrade.After)
+	}
+}
+
+func TestUpgradeFirmwareUnchanged(t *testing.T) {
+	server := firmwareServer("1.1.1 Build 160725 Rel.164033")
+	defer server.Close()
+
+	device := newFirmwareDevice("1.1.1 Build 160725 Rel.164033")
+	api := NewDeviceManager(devices.NewDeviceManager(),
+		WithTransport(device),
+		WithRetryPolicy(NoRetryPolicy()))
+
+	config := PlugConfig("mock")
+	upgrade, err := api.UpgradeFirmware(devices.NewDevice(&config), server.URL+"/firmware.bin",
+		WithPollInterval(time.Millisecond))
+	if !errors.Is(err, ErrFirmwareUnchanged) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if upgrade == nil || upgrade.Before != upgrade.After {
+		t.Fatalf("unexpected upgrade %+v", upgrade)
+	}
+}
+
+func TestUpgradeFirmwareFailures(t *testing.T) {
+	server := firmwareServer("1.2.6 Build 200727 Rel.121701")
+	defer server.Close()
+
+	device := newFirmwareDevice("1.1.1 Build 160725 Rel.164033")
+	api := NewDeviceManager(devices.NewDeviceManager(),
+		WithTransport(device),
+		WithRetryPolicy(NoRetryPolicy()))
+
+	config := PlugConfig("mock")
+	d := devices.NewDevice(&config)
+
+	if _, err := api.UpgradeFirmware(d, "ftp://example.com/firmware.bin"); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := api.UpgradeFirmware(d, server.URL+"/missing.bin"); !errors.Is(err, ErrProtocolOperationFailed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The device fails to download the firmware, which is reported right
+	// away rather than once the upgrade timed out.
+	device.failing = true
+	_, err := api.UpgradeFirmware(d, server.URL+"/firmware.bin",
+		WithPollInterval(time.Millisecond))
+	if !errors.Is(err, ErrFirmwareDownloadFailed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if device.polls != 1 {
+		t.Fatalf("download state was polled %d times", device.polls)
+	}
+	device.failing = false
+
+	// The download never completes.
+	device.polls = -1000000
+	_, err = api.UpgradeFirmware(d, server.URL+"/firmware.bin",
+		WithPollInterval(time.Millisecond),
+		WithUpgradeTimeout(50*time.Millisecond))
+	if !errors.Is(err, ErrFirmwareTimeout) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,416 @@
+package tplink
+
+import (
+	"context"
+	"crypto/rand"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+var ErrUnknownEffect = errors.New("lighting effect is not known")
+
+// effectsData holds the definitions of the effects built into the firmware
+// of KL430 light strips. The device only knows them by their definition, it
+// has no way to select a built-in effect by name.
+//
+//go:embed effects.json
+var effectsData []byte
+
+var builtinEffects = sync.OnceValue(func() []Effect {
+	var effects []Effect
+	if err := json.Unmarshal(effectsData, &effects); err != nil {
+		panic(fmt.Sprintf("invalid built-in effects: %s", err))
+	}
+
+	sort.Slice(effects, func(i, j int) bool {
+		return effects[i].Name < effects[j].Name
+	})
+	return effects
+})
+
+// BuiltinEffects returns the definitions of the built-in effects, sorted by
+// name.
+func BuiltinEffects() []Effect {
+	effects := builtinEffects()
+
+	out := make([]Effect, len(effects))
+	for i := range effects {
+		out[i] = effects[i].clone()
+	}
+	return out
+}
+
+// BuiltinEffect returns the built-in effect with the name, compared without
+// regard to case.
+func BuiltinEffect(name string) (Effect, error) {
+	for _, e := range builtinEffects() {
+		if strings.EqualFold(e.Name, name) {
+			return e.clone(), nil
+		}
+	}
+	return Effect{}, fmt.Errorf("%w: '%s'", ErrUnknownEffect, name)
+}
+
+// LightStrip controls the effects and the segments of a light strip such as
+// the KL430. The segment count and the model are taken from the sysinfo,
+// which is refreshed whenever the current effect is read.
+//
+// The firmware only reports the effect which is currently set, it cannot
+// list the custom effects it stores. The LightStrip keeps the definitions of
+// the custom effects uploaded through it or added with AddCustomEffect, so
+// they can be listed and selected again.
+type LightStrip struct {
+	custom []Effect
+	device devices.Addressable
+	info   *SystemInfo
+	mgr    *DeviceManager
+	mutex  sync.Mutex
+}
+
+func (m *DeviceManager) LightStrip(d *devices.Device) (*LightStrip, error) {
+	return m.LightStripContext(context.Background(), d)
+}
+
+func (m *DeviceManager) LightStripContext(ctx context.Context, d *devices.Device) (*LightStrip, error) {
+	info, err := m.SystemInfoContext(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Length <= 0 || info.LightingEffect == nil {
+		return nil, ErrUnsupportedFeature
+	}
+
+	return &LightStrip{device: d, info: info, mgr: m}, nil
+}
+
+// ColorTempRange returns the color temperatures in Kelvin supported by the
+// strip, looked up by model like Bulb.ColorTempRange.
+func (s *LightStrip) ColorTempRange() (int, int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r := colorTempRange(s.info.Model)
+	return r[0], r[1]
+}
+
+// Length is the number of segments of the strip.
+func (s *LightStrip) Length() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.info.Length
+}
+
+func (s *LightStrip) Effect() (*LightingEffectState, error) {
+	return s.EffectContext(context.Background())
+}
+
+// EffectContext returns the effect the strip is currently set to.
+func (s *LightStrip) EffectContext(ctx context.Context) (*LightingEffectState, error) {
+	info, err := s.mgr.SystemInfoContext(ctx, s.device)
+	if err != nil {
+		return nil, err
+	}
+	if info.LightingEffect == nil {
+		return nil, ErrUnsupportedFeature
+	}
+
+	s.mutex.Lock()
+	s.info = info
+	s.mutex.Unlock()
+
+	return info.LightingEffect, nil
+}
+
+// AddCustomEffect validates a custom effect, for example one stored by the
+// caller, and makes it known to the LightStrip without sending it. An id is
+// assigned when the effect has none.
+func (s *LightStrip) AddCustomEffect(e Effect) (*Effect, error) {
+	e, err := s.customEffect(e)
+	if err != nil {
+		return nil, err
+	}
+
+	s.remember(e)
+	return &e, nil
+}
+
+// CustomEffects lists the custom effects known to the LightStrip, in the
+// order they were added.
+func (s *LightStrip) CustomEffects() []Effect {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	out := make([]Effect, len(s.custom))
+	for i := range s.custom {
+		out[i] = s.custom[i].clone()
+	}
+	return out
+}
+
+// Effects lists the effects which can be selected, the built-in effects
+// followed by CustomEffects.
+func (s *LightStrip) Effects() []Effect {
+	return append(BuiltinEffects(), s.CustomEffects()...)
+}
+
+func (s *LightStrip) SetCustomEffect(id string) error {
+	return s.SetCustomEffectContext(context.Background(), id)
+}
+
+// SetCustomEffectContext starts the custom effect with the id.
+func (s *LightStrip) SetCustomEffectContext(ctx context.Context, id string) error {
+	s.mutex.Lock()
+	var e *Effect
+	for i := range s.custom {
+		if s.custom[i].Id == id {
+			clone := s.custom[i].clone()
+			e = &clone
+			break
+		}
+	}
+	s.mutex.Unlock()
+
+	if e == nil {
+		return fmt.Errorf("%w: custom effect '%s'", ErrUnknownEffect, id)
+	}
+	return s.send(ctx, *e)
+}
+
+func (s *LightStrip) SetEffect(name string) error {
+	return s.SetEffectContext(context.Background(), name)
+}
+
+// SetEffectContext starts the built-in effect with the name. Custom effects
+// are selected by id with SetCustomEffect.
+func (s *LightStrip) SetEffectContext(ctx context.Context, name string) error {
+	e, err := BuiltinEffect(name)
+	if err != nil {
+		return err
+	}
+
+	return s.send(ctx, e)
+}
+
+func (s *LightStrip) SetSegments(segments ...SegmentColor) error {
+	return s.SetSegmentsContext(context.Background(), segments...)
+}
+
+// SetSegmentsContext colors ranges of segments. Segments which are not part
+// of any range keep their color. A running effect is stopped by the device.
+func (s *LightStrip) SetSegmentsContext(ctx context.Context, segments ...SegmentColor) error {
+	if len(segments) == 0 {
+		return fmt.Errorf("%w: no segments", ErrInvalidParameter)
+	}
+
+	for _, c := range segments {
+		if err := s.validateSegment(c); err != nil {
+			return err
+		}
+	}
+
+	var r LightStripSegments
+	r.SetSegments(segments)
+
+	return s.mgr.DoContext(ctx, s.device, &r)
+}
+
+func (s *LightStrip) StopEffect() error {
+	return s.StopEffectContext(context.Background())
+}
+
+// StopEffectContext disables the effect, the strip returns to its previous
+// light state.
+func (s *LightStrip) StopEffectContext(ctx context.Context) error {
+	effects := builtinEffects()
+
+	e := effects[0].clone()
+	e.Enable = 0
+
+	return s.send(ctx, e)
+}
+
+func (s *LightStrip) UploadEffect(e Effect) (*Effect, error) {
+	return s.UploadEffectContext(context.Background(), e)
+}
+
+// UploadEffectContext validates a custom effect and starts it. An id is
+// assigned when the effect has none, the effect as sent is returned and can
+// be listed with CustomEffects afterwards.
+func (s *LightStrip) UploadEffectContext(ctx context.Context, e Effect) (*Effect, error) {
+	e, err := s.customEffect(e)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.send(ctx, e); err != nil {
+		return nil, err
+	}
+
+	s.remember(e)
+	return &e, nil
+}
+
+// customEffect prepares a copy of a custom effect for the device.
+func (s *LightStrip) customEffect(e Effect) (Effect, error) {
+	e = e.clone()
+	e.Custom = 1
+	e.Enable = 1
+
+	if e.Id == "" {
+		id, err := effectId()
+		if err != nil {
+			return e, err
+		}
+		e.Id = id
+	}
+
+	if err := s.validateEffect(&e); err != nil {
+		return e, err
+	}
+	return e, nil
+}
+
+// remember stores a custom effect, replacing an effect with the same id.
+func (s *LightStrip) remember(e Effect) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i := range s.custom {
+		if s.custom[i].Id == e.Id {
+			s.custom[i] = e.clone()
+			return
+		}
+	}
+	s.custom = append(s.custom, e.clone())
+}
+
+func (s *LightStrip) send(ctx context.Context, e Effect) error {
+	var r LightingEffectSet
+	r.SetEffect(e)
+
+	return s.mgr.DoContext(ctx, s.device, &r)
+}
+
+func (s *LightStrip) validateEffect(e *Effect) error {
+	if e.Name == "" {
+		return fmt.Errorf("%w: effect has no name", ErrInvalidParameter)
+	}
+	if e.Brightness < 0 || e.Brightness > 100 {
+		return fmt.Errorf("%w: brightness %d", ErrInvalidParameter, e.Brightness)
+	}
+	if e.Duration < 0 || e.Transition < 0 || e.RepeatTimes < 0 {
+		return fmt.Errorf("%w: negative timing in effect '%s'", ErrInvalidParameter, e.Name)
+	}
+
+	length := s.Length()
+	for _, segment := range e.Segments {
+		if segment < 0 || segment >= length {
+			return fmt.Errorf("%w: segment %d of %d", ErrInvalidParameter, segment, length)
+		}
+	}
+
+	switch e.Type {
+	case "sequence":
+		if len(e.Sequence) == 0 {
+			return fmt.Errorf("%w: sequence effect '%s' has no colors", ErrInvalidParameter, e.Name)
+		}
+		for _, color := range e.Sequence {
+			if err := validateHSB(color); err != nil {
+				return err
+			}
+		}
+	case "random":
+		for _, r := range [][]int{e.HueRange, e.SaturationRange, e.BrightnessRange} {
+			if len(r) != 2 || r[0] > r[1] {
+				return fmt.Errorf("%w: invalid range %v in effect '%s'", ErrInvalidParameter, r, e.Name)
+			}
+		}
+		for _, color := range append(e.InitStates, e.Backgrounds...) {
+			if err := validateHSB(color); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: effect type '%s'", ErrInvalidParameter, e.Type)
+	}
+
+	return nil
+}
+
+func (s *LightStrip) validateSegment(c SegmentColor) error {
+	length := s.Length()
+	if c.First < 0 || c.Last < c.First || c.Last >= length {
+		return fmt.Errorf("%w: segments %d-%d of %d", ErrInvalidParameter, c.First, c.Last, length)
+	}
+	if err := validateHSB([]int{c.Hue, c.Saturation, c.Brightness}); err != nil {
+		return err
+	}
+	if low, high := s.ColorTempRange(); c.ColorTemp != 0 && (c.ColorTemp < low || c.ColorTemp > high) {
+		return fmt.Errorf("%w: color temperature %dK outside of %dK-%dK",
+			ErrInvalidParameter, c.ColorTemp, low, high)
+	}
+	return nil
+}
+
+func (e Effect) clone() Effect {
+	clone := e
+	clone.Segments = append([]int(nil), e.Segments...)
+	clone.Sequence = cloneColors(e.Sequence)
+	clone.HueRange = append([]int(nil), e.HueRange...)
+	clone.SaturationRange = append([]int(nil), e.SaturationRange...)
+	clone.BrightnessRange = append([]int(nil), e.BrightnessRange...)
+	clone.TransitionRange = append([]int(nil), e.TransitionRange...)
+	clone.InitStates = cloneColors(e.InitStates)
+	clone.Backgrounds = cloneColors(e.Backgrounds)
+	return clone
+}
+
+func cloneColors(colors [][]int) [][]int {
+	if colors == nil {
+		return nil
+	}
+
+	out := make([][]int, len(colors))
+	for i := range colors {
+		out[i] = append([]int(nil), colors[i]...)
+	}
+	return out
+}
+
+// effectId returns a random id in the format of the ids of the built-in
+// effects, 32 letters.
+func effectId() (string, error) {
+	const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	max := big.NewInt(int64(len(letters)))
+	b := make([]byte, 32)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate effect id: %w", err)
+		}
+		b[i] = letters[n.Int64()]
+	}
+	return string(b), nil
+}
+
+// validateHSB checks a [hue, saturation, brightness] color.
+func validateHSB(color []int) error {
+	if len(color) != 3 {
+		return fmt.Errorf("%w: color %v", ErrInvalidParameter, color)
+	}
+	if color[0] < 0 || color[0] > 360 || color[1] < 0 || color[1] > 100 || color[2] < 0 || color[2] > 100 {
+		return fmt.Errorf("%w: color %v", ErrInvalidParameter, color)
+	}
+	return nil
+}
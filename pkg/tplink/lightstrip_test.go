@@ -0,0 +1,290 @@
+package tplink
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// stripModules emulates a light strip of the model with 16 segments. The last
+// effect sent is reflected in the sysinfo.
+func stripModules(model string) (mockModules, *[]map[string]interface{}) {
+	effect := map[string]interface{}{"enable": 0, "custom": 0}
+	var sent []map[string]interface{}
+
+	modules := bulbModules(model, 1, 1, 1)
+	sysinfo := modules["system"]["get_sysinfo"]
+	modules["system"]["get_sysinfo"] = func(params map[string]interface{}) interface{} {
+		info := sysinfo(params).(map[string]interface{})
+		info["length"] = 16
+		info["lighting_effect_state"] = effect
+		return info
+	}
+	modules[lightingEffectModule] = map[string]mockMethod{
+		"set_lighting_effect": func(params map[string]interface{}) interface{} {
+			sent = append(sent, params)
+			effect = map[string]interface{}{
+				"enable":     params["enable"],
+				"name":       params["name"],
+				"brightness": params["brightness"],
+				"custom":     params["custom"],
+				"id":         params["id"],
+			}
+			return map[string]interface{}{"err_code": 0}
+		},
+	}
+	modules[lightStripModule] = map[string]mockMethod{
+		"set_light_state": func(params map[string]interface{}) interface{} {
+			sent = append(sent, params)
+			return map[string]interface{}{"err_code": 0}
+		},
+	}
+	return modules, &sent
+}
+
+func TestBuiltinEffects(t *testing.T) {
+	effects := BuiltinEffects()
+	if len(effects) != 17 {
+		t.Fatalf("unexpected number of built-in effects %d", len(effects))
+	}
+
+	ids := make(map[string]bool)
+	for i, e := range effects {
+		if e.Id == "" || e.Name == "" || e.Custom != 0 || ids[e.Id] {
+			t.Fatalf("invalid built-in effect %+v", e)
+		}
+		ids[e.Id] = true
+		if i > 0 && effects[i-1].Name >= e.Name {
+			t.Fatalf("effects are not sorted: '%s' before '%s'", effects[i-1].Name, e.Name)
+		}
+	}
+
+	for _, name := range []string{"Bubbling Cauldron", "Grandma's Christmas Lights", "Hanukkah",
+		"Haunted Mansion", "Icicle", "Lightning", "Raindrop", "Spring", "Sunrise", "Sunset", "Valentines"} {
+		if _, err := BuiltinEffect(name); err != nil {
+			t.Fatalf("missing built-in effect: %s", err)
+		}
+	}
+
+	aurora, err := BuiltinEffect("aurora")
+	if err != nil || aurora.Name != "Aurora" || aurora.Type != "sequence" {
+		t.Fatalf("unexpected effect %+v: %v", aurora, err)
+	}
+
+	// Changes to a returned effect do not leak into the definitions.
+	aurora.Sequence[0][0] = 0
+	if again, _ := BuiltinEffect("Aurora"); again.Sequence[0][0] != 120 {
+		t.Fatalf("built-in effect was modified")
+	}
+
+	if _, err = BuiltinEffect("Disco"); !errors.Is(err, ErrUnknownEffect) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLightStrip(t *testing.T) {
+	modules, sent := stripModules("KL430(US)")
+	api, _ := newMockManager(modules.handler)
+
+	config := BulbConfig("mock")
+	strip, err := api.LightStrip(devices.NewDevice(&config))
+	if err != nil {
+		t.Fatalf("failed to get light strip: %s", err)
+	}
+	if strip.Length() != 16 || len(strip.Effects()) != len(BuiltinEffects()) {
+		t.Fatalf("unexpected light strip %d %v", strip.Length(), strip.Effects())
+	}
+
+	if err = strip.SetEffect("Rainbow"); err != nil {
+		t.Fatalf("failed to set effect: %s", err)
+	}
+	state, err := strip.Effect()
+	if err != nil {
+		t.Fatalf("failed to get effect: %s", err)
+	}
+	if state.Enable != 1 || state.Name != "Rainbow" || state.Custom != 0 {
+		t.Fatalf("unexpected effect state %+v", state)
+	}
+
+	custom, err := strip.UploadEffect(Effect{
+		Name:       "Police",
+		Type:       "sequence",
+		Brightness: 80,
+		Segments:   []int{0},
+		Transition: 200,
+		Sequence:   [][]int{{0, 100, 100}, {240, 100, 100}},
+	})
+	if err != nil {
+		t.Fatalf("failed to upload effect: %s", err)
+	}
+	if len(custom.Id) != 32 {
+		t.Fatalf("unexpected effect id '%s'", custom.Id)
+	}
+	if state, _ = strip.Effect(); state.Custom != 1 || state.Id != custom.Id {
+		t.Fatalf("unexpected effect state %+v", state)
+	}
+
+	if list := strip.CustomEffects(); len(list) != 1 || list[0].Name != "Police" || list[0].Custom != 1 {
+		t.Fatalf("unexpected custom effects %+v", list)
+	}
+	if effects := strip.Effects(); len(effects) != len(BuiltinEffects())+1 || effects[len(effects)-1].Id != custom.Id {
+		t.Fatalf("custom effect is not listed")
+	}
+
+	// A custom effect stored by the caller can be added and selected.
+	stored, err := strip.AddCustomEffect(Effect{
+		Id:       "HPjLkSAmwqPcHSyrEKhVhQJGQYdwZEvd",
+		Name:     "Candle",
+		Type:     "random",
+		Segments: []int{0},
+		HueRange: []int{30, 40}, SaturationRange: []int{90, 100}, BrightnessRange: []int{40, 80},
+	})
+	if err != nil {
+		t.Fatalf("failed to add effect: %s", err)
+	}
+	if err = strip.SetCustomEffect(stored.Id); err != nil {
+		t.Fatalf("failed to set custom effect: %s", err)
+	}
+	if state, _ = strip.Effect(); state.Name != "Candle" || state.Custom != 1 {
+		t.Fatalf("unexpected effect state %+v", state)
+	}
+	if err = strip.SetCustomEffect(custom.Id); err != nil {
+		t.Fatalf("failed to set custom effect: %s", err)
+	}
+	if state, _ = strip.Effect(); state.Id != custom.Id {
+		t.Fatalf("unexpected effect state %+v", state)
+	}
+	if err = strip.SetCustomEffect("unknown"); !errors.Is(err, ErrUnknownEffect) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err = strip.StopEffect(); err != nil {
+		t.Fatalf("failed to stop effect: %s", err)
+	}
+	if state, _ = strip.Effect(); state.Enable != 0 {
+		t.Fatalf("effect is still enabled")
+	}
+
+	err = strip.SetSegments(
+		SegmentColor{First: 0, Last: 7, Hue: 240, Saturation: 100, Brightness: 100},
+		SegmentColor{First: 8, Last: 15, Brightness: 50, ColorTemp: 2700})
+	if err != nil {
+		t.Fatalf("failed to set segments: %s", err)
+	}
+
+	last, _ := json.Marshal((*sent)[len(*sent)-1])
+	if string(last) != `{"groups":[[0,7,240,100,100,0],[8,15,0,0,50,2700]]}` {
+		t.Fatalf("unexpected request %s", last)
+	}
+}
+
+func TestEffectId(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id, err := effectId()
+		if err != nil {
+			t.Fatalf("failed to generate id: %s", err)
+		}
+		if len(id) != 32 || strings.Trim(id, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			t.Fatalf("unexpected id '%s'", id)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate id '%s'", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestLightStripValidation(t *testing.T) {
+	modules, sent := stripModules("KL430(US)")
+	api, _ := newMockManager(modules.handler)
+
+	config := BulbConfig("mock")
+	strip, err := api.LightStrip(devices.NewDevice(&config))
+	if err != nil {
+		t.Fatalf("failed to get light strip: %s", err)
+	}
+
+	if err = strip.SetEffect("Disco"); !errors.Is(err, ErrUnknownEffect) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	effects := []Effect{
+		{Type: "sequence", Sequence: [][]int{{0, 100, 100}}},
+		{Name: "Empty", Type: "sequence"},
+		{Name: "Hue", Type: "sequence", Sequence: [][]int{{400, 100, 100}}},
+		{Name: "Segment", Type: "sequence", Segments: []int{16}, Sequence: [][]int{{0, 100, 100}}},
+		{Name: "Random", Type: "random", HueRange: []int{10, 0}},
+		{Name: "Pulse", Type: "pulse"},
+	}
+	for _, e := range effects {
+		if _, err = strip.UploadEffect(e); !errors.Is(err, ErrInvalidParameter) {
+			t.Fatalf("unexpected error for %+v: %v", e, err)
+		}
+	}
+
+	segments := []SegmentColor{
+		{First: 0, Last: 16},
+		{First: 4, Last: 2},
+		{First: 0, Last: 0, Hue: 361},
+		{First: 0, Last: 0, ColorTemp: 1000},
+	}
+	for _, c := range segments {
+		if err = strip.SetSegments(c); !errors.Is(err, ErrInvalidParameter) {
+			t.Fatalf("unexpected error for %+v: %v", c, err)
+		}
+	}
+	if len(*sent) != 0 {
+		t.Fatalf("invalid requests were sent to the device")
+	}
+
+	// The color temperature is checked against the range of the model.
+	if err = strip.SetSegments(SegmentColor{First: 0, Last: 0, Brightness: 50, ColorTemp: 9000}); err != nil {
+		t.Fatalf("failed to set segments: %s", err)
+	}
+	modules, _ = stripModules("KL135(US)")
+	api, _ = newMockManager(modules.handler)
+	if strip, err = api.LightStrip(devices.NewDevice(&config)); err != nil {
+		t.Fatalf("failed to get light strip: %s", err)
+	}
+	if low, high := strip.ColorTempRange(); low != 2500 || high != 6500 {
+		t.Fatalf("unexpected color temperature range %d-%d", low, high)
+	}
+	if err = strip.SetSegments(SegmentColor{First: 0, Last: 0, Brightness: 50, ColorTemp: 9000}); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	api, _ = newMockManager(bulbModules("KL130(US)", 1, 1, 1).handler)
+	if _, err = api.LightStrip(devices.NewDevice(&config)); !errors.Is(err, ErrUnsupportedFeature) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLightStripConcurrent(t *testing.T) {
+	modules, _ := stripModules("KL430(US)")
+	api, _ := newMockManager(modules.handler)
+
+	config := BulbConfig("mock")
+	strip, err := api.LightStrip(devices.NewDevice(&config))
+	if err != nil {
+		t.Fatalf("failed to get light strip: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, _ = strip.Effect()
+		}()
+		go func() {
+			defer wg.Done()
+			_ = strip.SetSegments(SegmentColor{First: 0, Last: strip.Length() - 1, Brightness: 50})
+		}()
+	}
+	wg.Wait()
+}
@@ -1,11 +1,14 @@
 package tplink
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
+	"math"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Aralocke/tplink-smart-go/v1/pkg/utils"
@@ -16,32 +19,123 @@ import (
 
 var ErrProtocolOperationFailed = errors.New("operation on device returned an error")
 var ErrUnsupportedFeature = errors.New("feature is not supported by the device")
+var ErrDangerousOperation = errors.New("operation requires WithDangerousOperations")
+
+var (
+	deviceIdPattern   = regexp.MustCompile("^[0-9A-F]{40}$")
+	hardwareIdPattern = regexp.MustCompile("^[0-9A-F]{32}$")
+)
 
 type Feature int
 
 const (
 	FeatureElecMeter Feature = iota
+	FeatureAmbientLight
+	FeatureMotionSensor
 )
 
 var featureMap = map[Feature]string{
-	FeatureElecMeter: "ENE",
+	FeatureAmbientLight: "LAS",
+	FeatureElecMeter:    "ENE",
+	FeatureMotionSensor: "PIR",
+}
+
+type DeviceManagerOption func(*DeviceManagerOptions)
+
+type DeviceManagerOptions struct {
+	// AllowDangerous enables operations which can leave a device unusable
+	// with the vendor app or cloud, such as changing its device id.
+	AllowDangerous bool
+	CircuitBreaker BreakerPolicy
+	RetryPolicy    RetryPolicy
+	Transport      devices.Transport
+}
+
+// WithCircuitBreaker enables a circuit breaker for every device, see
+// BreakerPolicy.
+func WithCircuitBreaker(policy BreakerPolicy) DeviceManagerOption {
+	return func(o *DeviceManagerOptions) {
+		o.CircuitBreaker = policy
+	}
+}
+
+// WithDangerousOperations enables SetDeviceId and SetHardwareId. A device
+// with a changed id is no longer recognized by the vendor cloud.
+func WithDangerousOperations() DeviceManagerOption {
+	return func(o *DeviceManagerOptions) {
+		o.AllowDangerous = true
+	}
+}
+
+// WithRetryPolicy sets the retry policy used for every device which has no
+// policy of its own, see DeviceManager.SetRetryPolicy.
+func WithRetryPolicy(policy RetryPolicy) DeviceManagerOption {
+	return func(o *DeviceManagerOptions) {
+		o.RetryPolicy = policy
+	}
+}
+
+// WithTransport replaces the transport used to deliver requests to devices.
+func WithTransport(transport devices.Transport) DeviceManagerOption {
+	return func(o *DeviceManagerOptions) {
+		o.Transport = transport
+	}
+}
+
+func DefaultDeviceManagerOptions() *DeviceManagerOptions {
+	return &DeviceManagerOptions{
+		RetryPolicy: DefaultRetryPolicy(),
+	}
+}
+
+// DefaultTransport returns the transport used when none is configured, a
+// SyncSender speaking the encrypted TCP protocol.
+func DefaultTransport() devices.Transport {
+	return devices.NewSyncSender(
+		devices.WithEncoding(Decrypt, Encrypt),
+		devices.WithTimeout(30*time.Second))
 }
 
 type DeviceManager struct {
-	dvManager *devices.DeviceManager
-	devices   []*devices.Device
-	logger    *zap.Logger
+	allowDangerous bool
+	breakerPolicy  BreakerPolicy
+	breakers       map[string]*circuitBreaker
+	dvManager      *devices.DeviceManager
+	devices        []*devices.Device
+	logger         *zap.Logger
+	mutex          sync.RWMutex
+	retryPolicy    RetryPolicy
+	retryPolicies  map[string]RetryPolicy
+	transport      devices.Transport
 }
 
-func NewDeviceManager(dm *devices.DeviceManager) *DeviceManager {
+func NewDeviceManager(dm *devices.DeviceManager, opts ...DeviceManagerOption) *DeviceManager {
+	options := DefaultDeviceManagerOptions()
+	for _, option := range opts {
+		option(options)
+	}
+
+	if options.Transport == nil {
+		options.Transport = DefaultTransport()
+	}
+
 	dvManager := new(DeviceManager)
+	dvManager.allowDangerous = options.AllowDangerous
+	dvManager.breakerPolicy = options.CircuitBreaker
+	dvManager.breakers = make(map[string]*circuitBreaker)
 	dvManager.dvManager = dm
 	dvManager.logger = dm.Logger()
+	dvManager.retryPolicy = options.RetryPolicy
+	dvManager.retryPolicies = make(map[string]RetryPolicy)
+	dvManager.transport = options.Transport
 	return dvManager
 }
 
 func (m *DeviceManager) Devices() []*devices.Device {
-	return m.devices
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return append([]*devices.Device(nil), m.devices...)
 }
 
 func (m *DeviceManager) ElectricityMeter(d *devices.Device) (*EMeter, error) {
@@ -53,9 +147,13 @@ func (m *DeviceManager) ElectricityMeter(d *devices.Device) (*EMeter, error) {
 }
 
 func (m *DeviceManager) LoadDevice(cfg *devices.DeviceConfig) (*devices.Device, error) {
+	return m.LoadDeviceContext(context.Background(), cfg)
+}
+
+func (m *DeviceManager) LoadDeviceContext(ctx context.Context, cfg *devices.DeviceConfig) (*devices.Device, error) {
 	device := devices.NewDevice(cfg)
 
-	info, err := m.SystemInfo(device)
+	info, err := m.SystemInfoContext(ctx, device)
 	if err != nil {
 		return nil, err
 	}
@@ -70,15 +168,35 @@ func (m *DeviceManager) LoadDevice(cfg *devices.DeviceConfig) (*devices.Device,
 		devices.WithManufacturerId(info.ManufacturerId),
 		devices.WithModelVersion(info.Model),
 		devices.WithSoftwareVersion(info.SoftwareVersion),
+		devices.WithAlias(info.Alias),
+		devices.WithLedState(info.LedStatus == 0),
+		devices.WithLocation(float64(info.Latitude), float64(info.Longitude)),
+		devices.WithOnTime(info.UpTime),
+		devices.WithRelayState(info.RelayState != 0),
 	)
 
+	for _, child := range info.Children {
+		devices.NewChildDevice(device, childId(device, child.Id),
+			devices.WithAlias(child.Alias),
+			devices.WithOnTime(child.OnTime),
+			devices.WithRelayState(child.RelayState != 0),
+		)
+	}
+
+	m.mutex.Lock()
 	m.devices = append(m.devices, device)
+	m.mutex.Unlock()
+
 	return device, nil
 }
 
 func (m *DeviceManager) LoadDevices(devices []devices.DeviceConfig) error {
+	return m.LoadDevicesContext(context.Background(), devices)
+}
+
+func (m *DeviceManager) LoadDevicesContext(ctx context.Context, devices []devices.DeviceConfig) error {
 	for _, device := range devices {
-		if _, err := m.LoadDevice(&device); err != nil {
+		if _, err := m.LoadDeviceContext(ctx, &device); err != nil {
 			return err
 		}
 	}
@@ -90,6 +208,10 @@ func (m *DeviceManager) Logger() *zap.Logger {
 }
 
 func (m *DeviceManager) Marshal(d devices.Addressable, in interface{}) ([]byte, error) {
+	return m.MarshalContext(context.Background(), d, in)
+}
+
+func (m *DeviceManager) MarshalContext(ctx context.Context, d devices.Addressable, in interface{}) ([]byte, error) {
 	var err error
 	var s, res []byte
 
@@ -98,30 +220,36 @@ func (m *DeviceManager) Marshal(d devices.Addressable, in interface{}) ([]byte,
 		return []byte{}, err
 	}
 
+	if child, ok := d.(devices.ChildAddressable); ok && child.ChildId() != "" {
+		if s, err = withChildContext(s, child.ChildId()); err != nil {
+			return []byte{}, err
+		}
+	}
+
 	m.Logger().Debug("marshal message to device",
 		zap.String("device",
 			fmt.Sprintf("%s:%d", d.Address(), d.Port())),
 		zap.String("message", string(s)))
 
-	sender := devices.NewSyncSender(d,
-		devices.WithEncoding(Decrypt, Encrypt),
-		devices.WithTimeout(30*time.Second))
-
-	for i := 0; i < 5; i++ {
-		res, err = sender.Send(s)
-		if e, ok := err.(net.Error); ok {
-			if i == 5 || (!e.Timeout() && !e.Temporary()) {
-				return []byte{}, nil
-			}
+	if err = ctx.Err(); err != nil {
+		return []byte{}, err
+	}
 
-			m.Logger().Info("retrying device message",
-				zap.Int("retry", i),
-				zap.String("address",
-					fmt.Sprintf("%s:%d", d.Address(), d.Port())),
-				zap.Error(err))
-			continue
+	breaker := m.circuitBreaker(d)
+	probe, err := breaker.allow()
+	if err != nil {
+		return []byte{}, &RequestError{
+			Address:  deviceAddress(d),
+			Attempts: 0,
+			Err:      err,
 		}
-		break
+	}
+
+	res, err = m.send(ctx, d, s)
+	breaker.record(ctx, probe, err)
+
+	if err != nil {
+		return []byte{}, err
 	}
 
 	m.Logger().Debug("unmarshal message from device",
@@ -132,92 +260,245 @@ func (m *DeviceManager) Marshal(d devices.Addressable, in interface{}) ([]byte,
 	return res, nil
 }
 
+// send delivers the request to the device, retrying transient failures as
+// allowed by the device's RetryPolicy or the policy set on the context.
+func (m *DeviceManager) send(ctx context.Context, d devices.Addressable, s []byte) ([]byte, error) {
+	policy, ok := ctx.Value(retryPolicyKey{}).(RetryPolicy)
+	if !ok {
+		policy = m.RetryPolicy(d)
+	}
+	for attempt := 1; ; attempt++ {
+		release, err := m.dvManager.Acquire(ctx, d)
+		if err != nil {
+			return nil, &RequestError{
+				Address:  deviceAddress(d),
+				Attempts: attempt - 1,
+				Err:      err,
+			}
+		}
+
+		res, err := m.transport.Send(ctx, d, s)
+		release()
+
+		if err == nil {
+			return res, nil
+		}
+
+		if ctx.Err() != nil || attempt >= policy.Attempts() || !policy.IsRetryable(err) {
+			return nil, &RequestError{
+				Address:  deviceAddress(d),
+				Attempts: attempt,
+				Err:      err,
+			}
+		}
+
+		backoff := policy.Backoff(attempt)
+
+		m.Logger().Info("retrying device message",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.String("address",
+				fmt.Sprintf("%s:%d", d.Address(), d.Port())),
+			zap.Error(err))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, &RequestError{
+				Address:  deviceAddress(d),
+				Attempts: attempt,
+				Err:      ctx.Err(),
+			}
+		case <-timer.C:
+		}
+	}
+}
+
+// RetryPolicy returns the policy used for requests to the device.
+func (m *DeviceManager) RetryPolicy(d devices.Addressable) RetryPolicy {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if policy, ok := m.retryPolicies[deviceAddress(d)]; ok {
+		return policy
+	}
+	return m.retryPolicy
+}
+
+// SetRetryPolicy overrides the manager's retry policy for a single device.
+func (m *DeviceManager) SetRetryPolicy(d devices.Addressable, policy RetryPolicy) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.retryPolicies[deviceAddress(d)] = policy
+}
+
+func (m *DeviceManager) Transport() devices.Transport {
+	return m.transport
+}
+
 func (m *DeviceManager) Off(d *devices.Device) error {
-	return m.SetRelayState(d, false)
+	return m.OffContext(context.Background(), d)
+}
+
+func (m *DeviceManager) OffContext(ctx context.Context, d *devices.Device) error {
+	return m.SetRelayStateContext(ctx, d, false)
 }
 
 func (m *DeviceManager) On(d *devices.Device) error {
-	return m.SetRelayState(d, true)
+	return m.OnContext(context.Background(), d)
+}
+
+func (m *DeviceManager) OnContext(ctx context.Context, d *devices.Device) error {
+	return m.SetRelayStateContext(ctx, d, true)
 }
 
 func (m *DeviceManager) Reboot(d *devices.Device, delay int) error {
+	return m.RebootContext(context.Background(), d, delay)
+}
+
+func (m *DeviceManager) RebootContext(ctx context.Context, d *devices.Device, delay int) error {
 	var r SystemReboot
 	r.SetDelay(delay)
 
-	res, err := m.Marshal(d, r)
-	if err != nil {
+	return m.DoContext(ctx, d, &r)
+}
+
+func (m *DeviceManager) Reset(d *devices.Device, delay int) error {
+	return m.ResetContext(context.Background(), d, delay)
+}
+
+func (m *DeviceManager) ResetContext(ctx context.Context, d *devices.Device, delay int) error {
+	var r SystemReset
+	r.SetDelay(delay)
+
+	return m.DoContext(ctx, d, &r)
+}
+
+func (m *DeviceManager) SetAlias(d *devices.Device, alias string) error {
+	return m.SetAliasContext(context.Background(), d, alias)
+}
+
+func (m *DeviceManager) SetAliasContext(ctx context.Context, d *devices.Device, alias string) error {
+	var a SystemAlias
+	a.SetAlias(alias)
+
+	if err := m.DoContext(ctx, d, &a); err != nil {
 		return err
 	}
 
-	if err = json.Unmarshal(res, &r); err != nil {
-		return err
+	d.Apply(devices.WithAlias(alias))
+	return nil
+}
+
+func (m *DeviceManager) SetDeviceId(d *devices.Device, id string) error {
+	return m.SetDeviceIdContext(context.Background(), d, id)
+}
+
+// SetDeviceIdContext changes the device id, 40 hexadecimal characters. It
+// requires WithDangerousOperations.
+func (m *DeviceManager) SetDeviceIdContext(ctx context.Context, d *devices.Device, id string) error {
+	if !m.allowDangerous {
+		return ErrDangerousOperation
 	}
 
-	if r.ErrorCode() != 0 {
-		return ErrProtocolOperationFailed
+	id = strings.ToUpper(id)
+	if !deviceIdPattern.MatchString(id) {
+		return fmt.Errorf("%w: device id '%s'", ErrInvalidParameter, id)
 	}
 
+	var r SystemDeviceId
+	r.SetDeviceId(id)
+
+	if err := m.DoContext(ctx, d, &r); err != nil {
+		return err
+	}
+
+	d.Apply(devices.WithDeviceId(id))
 	return nil
 }
 
-func (m *DeviceManager) Reset(d *devices.Device, delay int) error {
-	var r SystemReset
-	r.SetDelay(delay)
+func (m *DeviceManager) SetHardwareId(d *devices.Device, id string) error {
+	return m.SetHardwareIdContext(context.Background(), d, id)
+}
 
-	res, err := m.Marshal(d, r)
-	if err != nil {
-		return err
+// SetHardwareIdContext changes the hardware id, 32 hexadecimal characters.
+// It requires WithDangerousOperations.
+func (m *DeviceManager) SetHardwareIdContext(ctx context.Context, d *devices.Device, id string) error {
+	if !m.allowDangerous {
+		return ErrDangerousOperation
 	}
 
-	if err = json.Unmarshal(res, &r); err != nil {
-		return err
+	id = strings.ToUpper(id)
+	if !hardwareIdPattern.MatchString(id) {
+		return fmt.Errorf("%w: hardware id '%s'", ErrInvalidParameter, id)
 	}
 
-	if r.ErrorCode() != 0 {
-		return ErrProtocolOperationFailed
+	var r SystemHardwareId
+	r.SetHardwareId(id)
+
+	if err := m.DoContext(ctx, d, &r); err != nil {
+		return err
 	}
 
+	d.Apply(devices.WithHardwareId(id))
 	return nil
 }
 
-func (m *DeviceManager) SetAlias(d *devices.Device, alias string) error {
-	var a SystemAlias
-	a.SetAlias(alias)
+func (m *DeviceManager) SetLed(d *devices.Device, on bool) error {
+	return m.SetLedContext(context.Background(), d, on)
+}
 
-	res, err := m.Marshal(d, a)
-	if err != nil {
+func (m *DeviceManager) SetLedContext(ctx context.Context, d *devices.Device, on bool) error {
+	var r SystemLedState
+	r.SetState(!on)
+
+	if err := m.DoContext(ctx, d, &r); err != nil {
 		return err
 	}
 
-	if err = json.Unmarshal(res, &a); err != nil {
-		return err
+	d.Apply(devices.WithLedState(on))
+	return nil
+}
+
+func (m *DeviceManager) SetLocation(d *devices.Device, latitude float64, longitude float64) error {
+	return m.SetLocationContext(context.Background(), d, latitude, longitude)
+}
+
+// SetLocationContext sets the location used for sunrise and sunset rules.
+func (m *DeviceManager) SetLocationContext(ctx context.Context, d *devices.Device, latitude float64, longitude float64) error {
+	if math.IsNaN(latitude) || latitude < -90 || latitude > 90 {
+		return fmt.Errorf("%w: latitude %f", ErrInvalidParameter, latitude)
+	}
+	if math.IsNaN(longitude) || longitude < -180 || longitude > 180 {
+		return fmt.Errorf("%w: longitude %f", ErrInvalidParameter, longitude)
 	}
 
-	if a.ErrorCode() != 0 {
-		return ErrProtocolOperationFailed
+	var r SystemLocation
+	r.SetLocation(latitude, longitude)
+
+	if err := m.DoContext(ctx, d, &r); err != nil {
+		return err
 	}
 
+	d.Apply(devices.WithLocation(latitude, longitude))
 	return nil
 }
 
 func (m *DeviceManager) SetRelayState(d *devices.Device, st bool) error {
+	return m.SetRelayStateContext(context.Background(), d, st)
+}
+
+func (m *DeviceManager) SetRelayStateContext(ctx context.Context, d *devices.Device, st bool) error {
 	var r SystemRelayState
-	r.System.RelayState.ErrorCode = 1
 	r.SetRelayState(st)
 
-	res, err := m.Marshal(d, r)
-	if err != nil {
+	if err := m.DoContext(ctx, d, &r); err != nil {
 		return err
 	}
 
-	if err = json.Unmarshal(res, &r); err != nil {
-		return err
-	}
-
-	if r.ErrorCode() != 0 {
-		return ErrProtocolOperationFailed
-	}
-
+	d.Apply(devices.WithRelayState(st))
 	return nil
 }
 
@@ -230,21 +511,15 @@ func (m *DeviceManager) Supports(d *devices.Device, feat Feature) bool {
 }
 
 func (m *DeviceManager) SystemInfo(d devices.Addressable) (*SystemInfo, error) {
-	var deviceInfo DeviceInfo
+	return m.SystemInfoContext(context.Background(), d)
+}
 
-	res, err := m.Marshal(d, deviceInfo)
-	if err != nil {
-		return nil, err
-	}
+func (m *DeviceManager) SystemInfoContext(ctx context.Context, d devices.Addressable) (*SystemInfo, error) {
+	var deviceInfo DeviceInfo
 
-	if err = json.Unmarshal(res, &deviceInfo); err != nil {
+	if err := m.DoContext(ctx, d, &deviceInfo); err != nil {
 		return nil, err
 	}
 
-	info := deviceInfo.SystemInfo()
-	if info.ErrorCode != 0 {
-		return nil, ErrProtocolOperationFailed
-	}
-
-	return info, nil
+	return deviceInfo.SystemInfo(), nil
 }
@@ -2,13 +2,150 @@ package tplink
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
 )
 
+type mockHandler func(d devices.Addressable, req map[string]interface{}) interface{}
+
+// mockTransport answers requests in-process. Requests are recorded so tests
+// can inspect exactly what was sent to the device.
+type mockTransport struct {
+	handler  mockHandler
+	mutex    sync.Mutex
+	requests []string
+}
+
+func newMockTransport(handler mockHandler) *mockTransport {
+	return &mockTransport{handler: handler}
+}
+
+func (t *mockTransport) Requests() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return append([]string(nil), t.requests...)
+}
+
+func (t *mockTransport) Send(ctx context.Context, d devices.Addressable, data []byte) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	t.mutex.Lock()
+	t.requests = append(t.requests, string(data))
+	t.mutex.Unlock()
+
+	var req map[string]interface{}
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(t.handler(d, req))
+}
+
+func newMockManager(handler mockHandler, opts ...DeviceManagerOption) (*DeviceManager, *mockTransport) {
+	transport := newMockTransport(handler)
+	api := NewDeviceManager(devices.NewDeviceManager(),
+		append([]DeviceManagerOption{WithTransport(transport)}, opts...)...)
+
+	return api, transport
+}
+
+type mockMethod func(params map[string]interface{}) interface{}
+
+// mockModules emulates the module/method dispatch of the device firmware,
+// including the errors returned for unknown modules and methods.
+type mockModules map[string]map[string]mockMethod
+
+func (modules mockModules) handler(_ devices.Addressable, req map[string]interface{}) interface{} {
+	res := make(map[string]interface{})
+
+	for module, body := range req {
+		if module == "context" {
+			continue
+		}
+
+		methods, ok := modules[module]
+		if !ok {
+			res[module] = map[string]interface{}{
+				"err_code": ErrCodeModuleNotSupported,
+				"err_msg":  "module not support",
+			}
+			continue
+		}
+
+		out := make(map[string]interface{})
+		calls, _ := body.(map[string]interface{})
+		for method, params := range calls {
+			fn, ok := methods[method]
+			if !ok {
+				out[method] = map[string]interface{}{
+					"err_code": ErrCodeMethodNotSupported,
+					"err_msg":  "member not support",
+				}
+				continue
+			}
+
+			args, _ := params.(map[string]interface{})
+			out[method] = fn(args)
+		}
+		res[module] = out
+	}
+
+	return res
+}
+
+func mockSuccess(map[string]interface{}) interface{} {
+	return map[string]interface{}{"err_code": 0}
+}
+
+// plugModules emulates a HS110 with energy monitoring.
+func plugModules() mockModules {
+	return mockModules{
+		"system": {
+			"get_sysinfo": func(map[string]interface{}) interface{} {
+				return map[string]interface{}{
+					"err_code": 0,
+					"alias":    "Mock Plug",
+					"deviceId": "8006000000000000000000000000000000000000",
+					"feature":  "TIM:ENE",
+					"model":    "HS110(US)",
+					"type":     "IOT.SMARTPLUGSWITCH",
+				}
+			},
+			"reboot":          mockSuccess,
+			"reset":           mockSuccess,
+			"set_dev_alias":   mockSuccess,
+			"set_relay_state": mockSuccess,
+		},
+		"emeter": {
+			"get_realtime": func(map[string]interface{}) interface{} {
+				return map[string]interface{}{
+					"err_code": 0,
+					"current":  0.5,
+					"voltage":  120.0,
+					"power":    60.0,
+					"total":    1.5,
+				}
+			},
+		},
+	}
+}
+
+func plugHandler(d devices.Addressable, req map[string]interface{}) interface{} {
+	return plugModules().handler(d, req)
+}
+
 type MockTpLinkDevice struct {
 	address string
 	test    *testing.T
@@ -122,3 +259,186 @@ func TestManagerStartStop(t *testing.T) {
 	   _ = device
 	*/
 }
+
+func TestManagerTransport(t *testing.T) {
+	api, transport := newMockManager(plugHandler)
+
+	config := PlugConfig("mock")
+	device, err := api.LoadDevice(&config)
+	if err != nil {
+		t.Fatalf("failed to load mock device: %s", err)
+	}
+	if device.DeviceId() != "8006000000000000000000000000000000000000" {
+		t.Fatalf("unexpected device id '%s'", device.DeviceId())
+	}
+
+	if err = api.On(device); err != nil {
+		t.Fatalf("failed to turn device on: %s", err)
+	}
+
+	meter, err := api.ElectricityMeter(device)
+	if err != nil {
+		t.Fatalf("failed to access electricity meter: %s", err)
+	}
+
+	energy, err := meter.Realtime()
+	if err != nil {
+		t.Fatalf("failed to read electricity meter: %s", err)
+	}
+	if !floatCompare(float64(energy.Power), 60.0) {
+		t.Fatalf("unexpected power reading %f", energy.Power)
+	}
+
+	requests := transport.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("unexpected number of requests: %d", len(requests))
+	}
+	if requests[1] != "{\"system\":{\"set_relay_state\":{\"state\":1}}}" {
+		t.Fatalf("unexpected relay state request '%s'", requests[1])
+	}
+}
+
+func TestManagerContextCancelled(t *testing.T) {
+	api, transport := newMockManager(plugHandler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	config := PlugConfig("mock")
+	if _, err := api.LoadDeviceContext(ctx, &config); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(transport.Requests()) != 0 {
+		t.Fatalf("request was sent with a cancelled context")
+	}
+}
+
+// settingsModules accepts the system settings of a plug.
+func settingsModules() mockModules {
+	modules := plugModules()
+	for _, method := range []string{"set_dev_location", "set_device_id", "set_hw_id", "set_led_off"} {
+		modules["system"][method] = mockSuccess
+	}
+	return modules
+}
+
+func TestManagerSetters(t *testing.T) {
+	api, transport := newMockManager(settingsModules().handler)
+
+	config := PlugConfig("mock")
+	d := devices.NewDevice(&config, devices.WithLedState(true))
+
+	if err := api.SetLed(d, false); err != nil {
+		t.Fatalf("failed to set led: %s", err)
+	}
+	if d.LedOn() {
+		t.Fatalf("led state was not refreshed")
+	}
+
+	if err := api.SetLocation(d, 52.37, 4.89); err != nil {
+		t.Fatalf("failed to set location: %s", err)
+	}
+	if lat, lon := d.Location(); lat != 52.37 || lon != 4.89 {
+		t.Fatalf("unexpected location %f/%f", lat, lon)
+	}
+
+	if err := api.SetAlias(d, "Kitchen"); err != nil {
+		t.Fatalf("failed to set alias: %s", err)
+	}
+	if err := api.SetRelayState(d, true); err != nil {
+		t.Fatalf("failed to set relay state: %s", err)
+	}
+	if d.Alias() != "Kitchen" || !d.RelayState() {
+		t.Fatalf("unexpected device state '%s' %t", d.Alias(), d.RelayState())
+	}
+
+	requests := transport.Requests()
+	if requests[0] != `{"system":{"set_led_off":{"off":1}}}` {
+		t.Fatalf("unexpected request %s", requests[0])
+	}
+
+	for _, loc := range [][2]float64{{91, 0}, {-91, 0}, {0, 181}, {0, -180.5}} {
+		if err := api.SetLocation(d, loc[0], loc[1]); !errors.Is(err, ErrInvalidParameter) {
+			t.Fatalf("unexpected error for %v: %v", loc, err)
+		}
+	}
+	if len(transport.Requests()) != len(requests) {
+		t.Fatalf("invalid location was sent to the device")
+	}
+}
+
+// TestManagerSettersConcurrent shares one device between goroutines which
+// change and read its cached state, run with -race.
+func TestManagerSettersConcurrent(t *testing.T) {
+	api, _ := newMockManager(settingsModules().handler)
+
+	config := PlugConfig("mock")
+	d := devices.NewDevice(&config)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(on bool) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := api.SetLed(d, on); err != nil {
+					t.Errorf("failed to set led: %s", err)
+					return
+				}
+			}
+		}(i%2 == 0)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_ = d.LedOn()
+				_ = d.Alias()
+				_, _ = d.Location()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestManagerSetIds(t *testing.T) {
+	const deviceId = "80060123456789ABCDEF0123456789ABCDEF0123"
+	const hardwareId = "0123456789ABCDEF0123456789ABCDEF"
+
+	api, transport := newMockManager(settingsModules().handler)
+
+	config := PlugConfig("mock")
+	d := devices.NewDevice(&config)
+
+	if err := api.SetDeviceId(d, deviceId); !errors.Is(err, ErrDangerousOperation) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := api.SetHardwareId(d, hardwareId); !errors.Is(err, ErrDangerousOperation) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(transport.Requests()) != 0 {
+		t.Fatalf("ids were sent without opt-in")
+	}
+
+	api, transport = newMockManager(settingsModules().handler, WithDangerousOperations())
+
+	if err := api.SetDeviceId(d, strings.ToLower(deviceId)); err != nil {
+		t.Fatalf("failed to set device id: %s", err)
+	}
+	if err := api.SetHardwareId(d, hardwareId); err != nil {
+		t.Fatalf("failed to set hardware id: %s", err)
+	}
+	if d.DeviceId() != deviceId || d.HardwareId() != hardwareId {
+		t.Fatalf("unexpected ids '%s' '%s'", d.DeviceId(), d.HardwareId())
+	}
+
+	for _, id := range []string{"", "8006", deviceId + "00", "Z" + deviceId[1:]} {
+		if err := api.SetDeviceId(d, id); !errors.Is(err, ErrInvalidParameter) {
+			t.Fatalf("unexpected error for '%s': %v", id, err)
+		}
+	}
+	if err := api.SetHardwareId(d, deviceId); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(transport.Requests()) != 2 {
+		t.Fatalf("invalid ids were sent to the device")
+	}
+}
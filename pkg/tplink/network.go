@@ -0,0 +1,63 @@
+package tplink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+	"go.uber.org/zap"
+)
+
+func (m *DeviceManager) JoinNetwork(d *devices.Device, ssid string, password string, keyType int) error {
+	return m.JoinNetworkContext(context.Background(), d, ssid, password, keyType)
+}
+
+// JoinNetworkContext sends the credentials of a wireless network to the
+// device. The device leaves its own access point once it accepted them, so
+// it has to be found on the new network afterwards. The credentials are
+// sent once, a connection dropped while waiting for the reply is taken as
+// the device switching networks.
+func (m *DeviceManager) JoinNetworkContext(ctx context.Context, d *devices.Device, ssid string, password string, keyType int) error {
+	if ssid == "" {
+		return fmt.Errorf("%w: empty network name", ErrInvalidParameter)
+	}
+	if keyType < KeyTypeNone || keyType > KeyTypeWPA2 {
+		return fmt.Errorf("%w: key type %d", ErrInvalidParameter, keyType)
+	}
+	if keyType != KeyTypeNone && password == "" {
+		return fmt.Errorf("%w: network '%s' requires a password", ErrInvalidParameter, ssid)
+	}
+
+	var r NetworkSettings
+	r.SetSettings(ssid, password)
+	r.SetKeyType(keyType)
+
+	err := m.DoContext(withRetryPolicy(ctx, NoRetryPolicy()), d, &r)
+	if ctx.Err() == nil && (errors.Is(err, syscall.ECONNRESET) || errors.Is(err, devices.ErrMessageTruncated)) {
+		m.Logger().Info("device dropped the connection after receiving the credentials",
+			zap.String("device", deviceAddress(d)),
+			zap.String("ssid", ssid),
+			zap.Error(err))
+		return nil
+	}
+	return err
+}
+
+func (m *DeviceManager) ScanNetworks(d *devices.Device, refresh bool) ([]AccessPoint, error) {
+	return m.ScanNetworksContext(context.Background(), d, refresh)
+}
+
+// ScanNetworksContext lists the wireless networks seen by the device. A
+// refresh makes the device scan again which takes a few seconds.
+func (m *DeviceManager) ScanNetworksContext(ctx context.Context, d *devices.Device, refresh bool) ([]AccessPoint, error) {
+	var r NetworkScan
+	r.SetRefresh(refresh)
+
+	if err := m.DoContext(ctx, d, &r); err != nil {
+		return nil, err
+	}
+
+	return r.GetAccessPoints(), nil
+}
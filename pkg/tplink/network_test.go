@@ -0,0 +1,50 @@
+package tplink
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// joinTransport fails every request with err, as a device does when it
+// leaves its access point.
+type joinTransport struct {
+	attempts int32
+	err      error
+}
+
+func (j *joinTransport) Send(context.Context, devices.Addressable, []byte) ([]byte, error) {
+	atomic.AddInt32(&j.attempts, 1)
+	return nil, j.err
+}
+
+func TestJoinNetworkSingleAttempt(t *testing.T) {
+	config := PlugConfig("mock")
+	d := devices.NewDevice(&config)
+
+	// The device switched networks before its reply was received.
+	transport := &joinTransport{err: syscall.ECONNRESET}
+	api := NewDeviceManager(devices.NewDeviceManager(), WithTransport(transport))
+
+	if err := api.JoinNetwork(d, "Home", "secret", KeyTypeWPA2); err != nil {
+		t.Fatalf("failed to join network: %s", err)
+	}
+	if transport.attempts != 1 {
+		t.Fatalf("credentials were sent %d times", transport.attempts)
+	}
+
+	// The credentials never reached the device.
+	transport = &joinTransport{err: syscall.ECONNREFUSED}
+	api = NewDeviceManager(devices.NewDeviceManager(), WithTransport(transport))
+
+	if err := api.JoinNetwork(d, "Home", "secret", KeyTypeWPA2); !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transport.attempts != 1 {
+		t.Fatalf("credentials were sent %d times", transport.attempts)
+	}
+}
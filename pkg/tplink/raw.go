@@ -0,0 +1,51 @@
+package tplink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+var ErrInvalidRequest = errors.New("request must be a JSON object")
+
+func (m *DeviceManager) Raw(d devices.Addressable, in interface{}) (map[string]interface{}, error) {
+	return m.RawContext(context.Background(), d, in)
+}
+
+// RawContext sends an arbitrary request to the device and returns the
+// decoded response. The request is either a JSON document given as a
+// string, []byte or json.RawMessage, or any value which marshals to a JSON
+// object such as a map. Error codes in the response are not interpreted.
+func (m *DeviceManager) RawContext(ctx context.Context, d devices.Addressable, in interface{}) (map[string]interface{}, error) {
+	switch v := in.(type) {
+	case string:
+		in = json.RawMessage(v)
+	case []byte:
+		in = json.RawMessage(v)
+	}
+
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRequest, err)
+	}
+
+	var request map[string]json.RawMessage
+	if err = json.Unmarshal(data, &request); err != nil || request == nil {
+		return nil, ErrInvalidRequest
+	}
+
+	res, err := m.MarshalContext(ctx, d, json.RawMessage(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var response map[string]interface{}
+	if err = json.Unmarshal(res, &response); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -0,0 +1,59 @@
+package tplink
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+func TestRaw(t *testing.T) {
+	api, transport := newMockManager(cloudModules().handler)
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	requests := []interface{}{
+		"{\"cnCloud\": {\"get_info\": {}}}",
+		[]byte("{\"cnCloud\":{\"get_info\":{}}}"),
+		map[string]interface{}{
+			"cnCloud": map[string]interface{}{"get_info": map[string]interface{}{}},
+		},
+	}
+
+	for _, request := range requests {
+		res, err := api.Raw(device, request)
+		if err != nil {
+			t.Fatalf("failed to send raw request: %s", err)
+		}
+
+		cloud, _ := res["cnCloud"].(map[string]interface{})
+		info, _ := cloud["get_info"].(map[string]interface{})
+		if info["server"] != "n-devs.tplinkcloud.com" {
+			t.Fatalf("unexpected response %v", res)
+		}
+	}
+
+	for _, request := range transport.Requests() {
+		if request != "{\"cnCloud\":{\"get_info\":{}}}" {
+			t.Fatalf("unexpected request '%s'", request)
+		}
+	}
+}
+
+func TestRawInvalid(t *testing.T) {
+	api, transport := newMockManager(plugHandler)
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	for _, request := range []interface{}{"{\"system\":", "[1, 2]", "null", 42} {
+		if _, err := api.Raw(device, request); !errors.Is(err, ErrInvalidRequest) {
+			t.Fatalf("unexpected error for %v: %v", request, err)
+		}
+	}
+
+	if len(transport.Requests()) != 0 {
+		t.Fatalf("invalid requests were sent to the device")
+	}
+}
@@ -0,0 +1,173 @@
+package tplink
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// flakyTransport fails the first 'failures' requests with err before
+// handing requests over to the wrapped transport.
+type flakyTransport struct {
+	attempts  int
+	err       error
+	failures  int
+	transport devices.Transport
+}
+
+func (t *flakyTransport) Send(ctx context.Context, d devices.Addressable, data []byte) ([]byte, error) {
+	t.attempts++
+	if t.attempts <= t.failures {
+		return nil, t.err
+	}
+	return t.transport.Send(ctx, d, data)
+}
+
+func fastRetryPolicy(attempts int) RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MaxAttempts = attempts
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+	}
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+	}
+	for i, want := range expected {
+		if backoff := policy.Backoff(i + 1); backoff != want {
+			t.Fatalf("unexpected backoff %s for attempt %d", backoff, i+1)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		backoff := policy.Backoff(1)
+		if backoff < 50*time.Millisecond || backoff > 150*time.Millisecond {
+			t.Fatalf("backoff %s outside of jitter range", backoff)
+		}
+	}
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	transport := &flakyTransport{
+		err:       os.ErrDeadlineExceeded,
+		failures:  2,
+		transport: newMockTransport(plugHandler),
+	}
+
+	api := NewDeviceManager(devices.NewDeviceManager(),
+		WithTransport(transport),
+		WithRetryPolicy(fastRetryPolicy(3)))
+
+	config := PlugConfig("mock")
+	if _, err := api.SystemInfo(devices.NewDevice(&config)); err != nil {
+		t.Fatalf("failed to load system info: %s", err)
+	}
+	if transport.attempts != 3 {
+		t.Fatalf("unexpected number of attempts: %d", transport.attempts)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	transport := &flakyTransport{
+		err:       syscall.ECONNREFUSED,
+		failures:  10,
+		transport: newMockTransport(plugHandler),
+	}
+
+	api := NewDeviceManager(devices.NewDeviceManager(),
+		WithTransport(transport),
+		WithRetryPolicy(fastRetryPolicy(4)))
+
+	config := PlugConfig("10.0.0.1")
+	_, err := api.SystemInfo(devices.NewDevice(&config))
+
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requestErr.Attempts != 4 || requestErr.Address != "10.0.0.1:9999" {
+		t.Fatalf("unexpected request error: %s", requestErr)
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Fatalf("underlying error was not returned: %v", err)
+	}
+}
+
+func TestRetryPermanentError(t *testing.T) {
+	transport := &flakyTransport{
+		err:       devices.ErrDecodingFailed,
+		failures:  10,
+		transport: newMockTransport(plugHandler),
+	}
+
+	api := NewDeviceManager(devices.NewDeviceManager(),
+		WithTransport(transport),
+		WithRetryPolicy(fastRetryPolicy(5)))
+
+	config := PlugConfig("mock")
+	device := devices.NewDevice(&config)
+
+	if _, err := api.SystemInfo(device); !errors.Is(err, devices.ErrDecodingFailed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transport.attempts != 1 {
+		t.Fatalf("unexpected number of attempts: %d", transport.attempts)
+	}
+
+	// A per-device policy may choose to retry any error.
+	policy := fastRetryPolicy(2)
+	policy.Retryable = func(error) bool { return true }
+	api.SetRetryPolicy(device, policy)
+
+	transport.attempts = 0
+	if _, err := api.SystemInfo(device); !errors.Is(err, devices.ErrDecodingFailed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transport.attempts != 2 {
+		t.Fatalf("unexpected number of attempts: %d", transport.attempts)
+	}
+}
+
+func TestRetryBackoffCancelled(t *testing.T) {
+	transport := &flakyTransport{
+		err:       os.ErrDeadlineExceeded,
+		failures:  10,
+		transport: newMockTransport(plugHandler),
+	}
+
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Minute
+
+	api := NewDeviceManager(devices.NewDeviceManager(),
+		WithTransport(transport),
+		WithRetryPolicy(policy))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	config := PlugConfig("mock")
+	if _, err := api.SystemInfoContext(ctx, devices.NewDevice(&config)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transport.attempts != 1 {
+		t.Fatalf("unexpected number of attempts: %d", transport.attempts)
+	}
+}
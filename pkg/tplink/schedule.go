@@ -0,0 +1,294 @@
+package tplink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+const (
+	scheduleModule     = "schedule"
+	bulbScheduleModule = "smartlife.iot.common.schedule"
+)
+
+// Weekdays is a mask of the days a rule is active on, see ScheduleRule.
+type Weekdays uint8
+
+const (
+	Sunday Weekdays = 1 << iota
+	Monday
+	Tuesday
+	Wednesday
+	Thursday
+	Friday
+	Saturday
+
+	WorkDays = Monday | Tuesday | Wednesday | Thursday | Friday
+	Weekend  = Saturday | Sunday
+	EveryDay = WorkDays | Weekend
+)
+
+func (w Weekdays) Has(day time.Weekday) bool {
+	return w&(1<<uint(day)) != 0
+}
+
+// MarshalJSON encodes the mask as the 'wday' list of the device, seven flags
+// starting on Sunday.
+func (w Weekdays) MarshalJSON() ([]byte, error) {
+	var days [7]int
+	for i := range days {
+		if w.Has(time.Weekday(i)) {
+			days[i] = 1
+		}
+	}
+	return json.Marshal(days)
+}
+
+func (w *Weekdays) UnmarshalJSON(data []byte) error {
+	var days []int
+	if err := json.Unmarshal(data, &days); err != nil {
+		return err
+	}
+
+	*w = 0
+	for i, day := range days {
+		if day != 0 && i < 7 {
+			*w |= 1 << uint(i)
+		}
+	}
+	return nil
+}
+
+// ScheduleTime selects how the start or end time of a rule is interpreted.
+type ScheduleTime int
+
+const (
+	ScheduleTimeNone    ScheduleTime = -1
+	ScheduleTimeMinutes ScheduleTime = 0
+	ScheduleTimeSunrise ScheduleTime = 1
+	ScheduleTimeSunset  ScheduleTime = 2
+)
+
+type ScheduleAction int
+
+const (
+	ScheduleActionNone ScheduleAction = -1
+	ScheduleActionOff  ScheduleAction = 0
+	ScheduleActionOn   ScheduleAction = 1
+)
+
+// ScheduleRule is a rule stored on the device. The start and end are either
+// minutes after midnight or, for sunrise and sunset, an offset in minutes.
+// A rule which does not repeat runs once on Year, Month and Day.
+type ScheduleRule struct {
+	Id          string           `json:"id,omitempty"`
+	Name        string           `json:"name"`
+	Enable      int              `json:"enable"`
+	Days        Weekdays         `json:"wday"`
+	StartOpt    ScheduleTime     `json:"stime_opt"`
+	StartMin    int              `json:"smin"`
+	StartOffset int              `json:"soffset,omitempty"`
+	StartAction ScheduleAction   `json:"sact"`
+	StartLight  *LightTransition `json:"s_light,omitempty"`
+	EndOpt      ScheduleTime     `json:"etime_opt"`
+	EndMin      int              `json:"emin"`
+	EndOffset   int              `json:"eoffset,omitempty"`
+	EndAction   ScheduleAction   `json:"eact"`
+	Repeat      int              `json:"repeat"`
+	Year        int              `json:"year"`
+	Month       int              `json:"month"`
+	Day         int              `json:"day"`
+	Force       int              `json:"force"`
+	Latitude    float32          `json:"latitude"`
+	Longitude   float32          `json:"longitude"`
+}
+
+// NewScheduleRule returns an enabled rule which switches the device on or
+// off every week on days at the given minute after midnight.
+func NewScheduleRule(name string, days Weekdays, minute int, on bool) ScheduleRule {
+	action := ScheduleActionOff
+	if on {
+		action = ScheduleActionOn
+	}
+
+	return ScheduleRule{
+		Name:        name,
+		Enable:      1,
+		Days:        days,
+		StartOpt:    ScheduleTimeMinutes,
+		StartMin:    minute,
+		StartAction: action,
+		EndOpt:      ScheduleTimeNone,
+		EndAction:   ScheduleActionNone,
+		Repeat:      1,
+	}
+}
+
+func (r *ScheduleRule) Enabled() bool {
+	return r.Enable != 0
+}
+
+// Validate checks the rule before it is sent, the firmware accepts some
+// invalid rules and never runs them.
+func (r *ScheduleRule) Validate() error {
+	if r.StartAction == ScheduleActionNone {
+		return fmt.Errorf("%w: rule has no start action", ErrInvalidParameter)
+	}
+	if r.Repeat != 0 && r.Days == 0 {
+		return fmt.Errorf("%w: repeating rule has no weekdays", ErrInvalidParameter)
+	}
+	if r.Repeat == 0 && (r.Year == 0 || r.Month < 1 || r.Month > 12 || r.Day < 1 || r.Day > 31) {
+		return fmt.Errorf("%w: rule runs once but has no date", ErrInvalidParameter)
+	}
+
+	if err := validateScheduleTime("start", r.StartOpt, r.StartMin, r.StartOffset); err != nil {
+		return err
+	}
+	if r.StartOpt == ScheduleTimeNone {
+		return fmt.Errorf("%w: rule has no start time", ErrInvalidParameter)
+	}
+
+	if r.EndAction != ScheduleActionNone {
+		if err := validateScheduleTime("end", r.EndOpt, r.EndMin, r.EndOffset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateScheduleTime(name string, opt ScheduleTime, minute int, offset int) error {
+	switch opt {
+	case ScheduleTimeNone:
+	case ScheduleTimeMinutes:
+		if minute < 0 || minute >= 24*60 {
+			return fmt.Errorf("%w: %s minute %d", ErrInvalidParameter, name, minute)
+		}
+	case ScheduleTimeSunrise, ScheduleTimeSunset:
+		if offset <= -24*60 || offset >= 24*60 {
+			return fmt.Errorf("%w: %s offset %d", ErrInvalidParameter, name, offset)
+		}
+	default:
+		return fmt.Errorf("%w: %s time option %d", ErrInvalidParameter, name, opt)
+	}
+	return nil
+}
+
+type ScheduleRuleList struct {
+	errorCode
+	Enable  int            `json:"enable"`
+	Rules   []ScheduleRule `json:"rule_list"`
+	Version int            `json:"version,omitempty"`
+}
+
+func (l *ScheduleRuleList) Enabled() bool {
+	return l.Enable != 0
+}
+
+type scheduleRuleId struct {
+	Id string `json:"id"`
+}
+
+type scheduleEnable struct {
+	Enable int `json:"enable"`
+}
+
+// Schedule manages the rules stored on the device. The rules are run by the
+// device itself and keep working while no controller is connected.
+type Schedule struct {
+	device devices.Addressable
+	mgr    *DeviceManager
+	module string
+}
+
+func (m *DeviceManager) Schedule(d *devices.Device) *Schedule {
+	module := scheduleModule
+	if d.DeviceType() == devices.BulbDevice {
+		module = bulbScheduleModule
+	}
+
+	return &Schedule{device: d, mgr: m, module: module}
+}
+
+func (s *Schedule) AddRule(rule ScheduleRule) (string, error) {
+	return s.AddRuleContext(context.Background(), rule)
+}
+
+// AddRuleContext stores a new rule and returns the id assigned to it by the
+// device.
+func (s *Schedule) AddRuleContext(ctx context.Context, rule ScheduleRule) (string, error) {
+	if err := rule.Validate(); err != nil {
+		return "", err
+	}
+	rule.Id = ""
+
+	var res scheduleRuleId
+	if err := s.mgr.call(ctx, s.device, s.module, "add_rule", rule, &res); err != nil {
+		return "", err
+	}
+
+	return res.Id, nil
+}
+
+func (s *Schedule) DeleteAllRules() error {
+	return s.DeleteAllRulesContext(context.Background())
+}
+
+func (s *Schedule) DeleteAllRulesContext(ctx context.Context) error {
+	return s.mgr.call(ctx, s.device, s.module, "delete_all_rules", nil, nil)
+}
+
+func (s *Schedule) DeleteRule(id string) error {
+	return s.DeleteRuleContext(context.Background(), id)
+}
+
+func (s *Schedule) DeleteRuleContext(ctx context.Context, id string) error {
+	return s.mgr.call(ctx, s.device, s.module, "delete_rule", scheduleRuleId{Id: id}, nil)
+}
+
+func (s *Schedule) EditRule(rule ScheduleRule) error {
+	return s.EditRuleContext(context.Background(), rule)
+}
+
+// EditRuleContext replaces the rule with the id of the given rule.
+func (s *Schedule) EditRuleContext(ctx context.Context, rule ScheduleRule) error {
+	if rule.Id == "" {
+		return fmt.Errorf("%w: rule has no id", ErrInvalidParameter)
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	return s.mgr.call(ctx, s.device, s.module, "edit_rule", rule, nil)
+}
+
+func (s *Schedule) Rules() (*ScheduleRuleList, error) {
+	return s.RulesContext(context.Background())
+}
+
+func (s *Schedule) RulesContext(ctx context.Context) (*ScheduleRuleList, error) {
+	var rules ScheduleRuleList
+	if err := s.mgr.call(ctx, s.device, s.module, "get_rules", nil, &rules); err != nil {
+		return nil, err
+	}
+
+	return &rules, nil
+}
+
+func (s *Schedule) SetEnabled(enabled bool) error {
+	return s.SetEnabledContext(context.Background(), enabled)
+}
+
+// SetEnabledContext enables or disables all rules at once without changing
+// the rules themselves.
+func (s *Schedule) SetEnabledContext(ctx context.Context, enabled bool) error {
+	enable := 0
+	if enabled {
+		enable = 1
+	}
+
+	return s.mgr.call(ctx, s.device, s.module, "set_overall_enable", scheduleEnable{Enable: enable}, nil)
+}
@@ -0,0 +1,198 @@
+package tplink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// scheduleModules emulates the rule storage of the schedule module.
+func scheduleModules(module string) mockModules {
+	var rules []map[string]interface{}
+	enable := 1
+	next := 0
+
+	indexOf := func(id interface{}) int {
+		for i, rule := range rules {
+			if rule["id"] == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	return mockModules{
+		module: {
+			"get_rules": func(map[string]interface{}) interface{} {
+				return map[string]interface{}{
+					"err_code":  0,
+					"enable":    enable,
+					"rule_list": rules,
+					"version":   2,
+				}
+			},
+			"add_rule": func(params map[string]interface{}) interface{} {
+				next++
+				params["id"] = fmt.Sprintf("RULE%02d", next)
+				rules = append(rules, params)
+				return map[string]interface{}{"err_code": 0, "id": params["id"]}
+			},
+			"edit_rule": func(params map[string]interface{}) interface{} {
+				i := indexOf(params["id"])
+				if i < 0 {
+					return map[string]interface{}{"err_code": -14, "err_msg": "entry not exist"}
+				}
+				rules[i] = params
+				return map[string]interface{}{"err_code": 0}
+			},
+			"delete_rule": func(params map[string]interface{}) interface{} {
+				i := indexOf(params["id"])
+				if i < 0 {
+					return map[string]interface{}{"err_code": -14, "err_msg": "entry not exist"}
+				}
+				rules = append(rules[:i], rules[i+1:]...)
+				return map[string]interface{}{"err_code": 0}
+			},
+			"delete_all_rules": func(map[string]interface{}) interface{} {
+				rules = nil
+				return map[string]interface{}{"err_code": 0}
+			},
+			"set_overall_enable": func(params map[string]interface{}) interface{} {
+				v, _ := params["enable"].(float64)
+				enable = int(v)
+				return map[string]interface{}{"err_code": 0}
+			},
+		},
+	}
+}
+
+func TestWeekdays(t *testing.T) {
+	data, err := json.Marshal(WorkDays)
+	if err != nil {
+		t.Fatalf("failed to marshal weekdays: %s", err)
+	}
+	if string(data) != "[0,1,1,1,1,1,0]" {
+		t.Fatalf("unexpected weekdays '%s'", data)
+	}
+
+	var days Weekdays
+	if err = json.Unmarshal([]byte("[1,0,0,0,0,0,1]"), &days); err != nil {
+		t.Fatalf("failed to unmarshal weekdays: %s", err)
+	}
+	if days != Weekend || !days.Has(time.Saturday) || days.Has(time.Monday) {
+		t.Fatalf("unexpected weekdays %07b", days)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	api, transport := newMockManager(scheduleModules(scheduleModule).handler)
+
+	config := PlugConfig("mock")
+	schedule := api.Schedule(devices.NewDevice(&config))
+
+	id, err := schedule.AddRule(NewScheduleRule("lights on", WorkDays, 7*60, true))
+	if err != nil {
+		t.Fatalf("failed to add rule: %s", err)
+	}
+
+	sunset := NewScheduleRule("lights off", EveryDay, 0, false)
+	sunset.StartOpt = ScheduleTimeSunset
+	sunset.StartOffset = 30
+	if _, err = schedule.AddRule(sunset); err != nil {
+		t.Fatalf("failed to add rule: %s", err)
+	}
+
+	list, err := schedule.Rules()
+	if err != nil {
+		t.Fatalf("failed to get rules: %s", err)
+	}
+	if !list.Enabled() || len(list.Rules) != 2 {
+		t.Fatalf("unexpected rules %+v", list)
+	}
+
+	rule := list.Rules[0]
+	if rule.Id != id || rule.Days != WorkDays || rule.StartMin != 420 || rule.StartAction != ScheduleActionOn {
+		t.Fatalf("unexpected rule %+v", rule)
+	}
+	if list.Rules[1].StartOpt != ScheduleTimeSunset || list.Rules[1].StartOffset != 30 {
+		t.Fatalf("unexpected rule %+v", list.Rules[1])
+	}
+
+	rule.StartMin = 8 * 60
+	if err = schedule.EditRule(rule); err != nil {
+		t.Fatalf("failed to edit rule: %s", err)
+	}
+	if err = schedule.DeleteRule(list.Rules[1].Id); err != nil {
+		t.Fatalf("failed to delete rule: %s", err)
+	}
+	if err = schedule.DeleteRule("missing"); !errors.Is(err, ErrProtocolOperationFailed) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = schedule.SetEnabled(false); err != nil {
+		t.Fatalf("failed to disable schedule: %s", err)
+	}
+
+	list, err = schedule.Rules()
+	if err != nil {
+		t.Fatalf("failed to get rules: %s", err)
+	}
+	if list.Enabled() || len(list.Rules) != 1 || list.Rules[0].StartMin != 480 {
+		t.Fatalf("unexpected rules %+v", list)
+	}
+
+	if err = schedule.DeleteAllRules(); err != nil {
+		t.Fatalf("failed to delete rules: %s", err)
+	}
+
+	for _, request := range transport.Requests() {
+		if !strings.HasPrefix(request, "{\"schedule\":") {
+			t.Fatalf("unexpected request '%s'", request)
+		}
+	}
+}
+
+func TestScheduleBulb(t *testing.T) {
+	api, transport := newMockManager(scheduleModules(bulbScheduleModule).handler)
+
+	config := BulbConfig("mock")
+	schedule := api.Schedule(devices.NewDevice(&config))
+
+	if _, err := schedule.Rules(); err != nil {
+		t.Fatalf("failed to get rules: %s", err)
+	}
+
+	requests := transport.Requests()
+	if len(requests) != 1 || requests[0] != "{\"smartlife.iot.common.schedule\":{\"get_rules\":{}}}" {
+		t.Fatalf("unexpected requests %v", requests)
+	}
+}
+
+func TestScheduleRuleValidate(t *testing.T) {
+	api, transport := newMockManager(scheduleModules(scheduleModule).handler)
+
+	config := PlugConfig("mock")
+	schedule := api.Schedule(devices.NewDevice(&config))
+
+	late := NewScheduleRule("late", EveryDay, 24*60, true)
+	noDays := NewScheduleRule("never", 0, 60, true)
+	once := NewScheduleRule("once", 0, 60, true)
+	once.Repeat = 0
+
+	for _, rule := range []ScheduleRule{late, noDays, once} {
+		if _, err := schedule.AddRule(rule); !errors.Is(err, ErrInvalidParameter) {
+			t.Fatalf("unexpected error for %s: %v", rule.Name, err)
+		}
+	}
+	if err := schedule.EditRule(NewScheduleRule("no id", EveryDay, 60, true)); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(transport.Requests()) != 0 {
+		t.Fatalf("invalid rules were sent to the device")
+	}
+}
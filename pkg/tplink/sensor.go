@@ -0,0 +1,217 @@
+package tplink
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// MotionRange selects one of the sensitivity presets of the motion sensor,
+// MotionRangeCustom uses the sensitivity set with SetCustomSensitivity.
+type MotionRange int
+
+const (
+	MotionRangeFar MotionRange = iota
+	MotionRangeMid
+	MotionRangeNear
+	MotionRangeCustom
+)
+
+func (c MotionConfig) Enabled() bool {
+	return c.Enable != 0
+}
+
+// Sensitivity is the threshold of the selected range, higher values trigger
+// on smaller movements.
+func (c MotionConfig) Sensitivity() int {
+	if c.Range >= 0 && c.Range < len(c.Thresholds) {
+		return c.Thresholds[c.Range]
+	}
+	return 0
+}
+
+func (c MotionConfig) Cooldown() time.Duration {
+	return time.Duration(c.ColdTime) * time.Millisecond
+}
+
+func (c AmbientLightConfig) Enabled() bool {
+	return c.Enable != 0
+}
+
+// MotionSensor is the PIR module of switches such as the ES20M and KS200M.
+type MotionSensor struct {
+	device devices.Addressable
+	mgr    *DeviceManager
+}
+
+// AmbientLightSensor is the LAS module of switches such as the ES20M and
+// KS200M. The device reports brightness as a percentage of the sensor range,
+// it has no reading in lux.
+type AmbientLightSensor struct {
+	device devices.Addressable
+	mgr    *DeviceManager
+}
+
+func (m *DeviceManager) AmbientLightSensor(d *devices.Device) (*AmbientLightSensor, error) {
+	if !m.Supports(d, FeatureAmbientLight) {
+		return nil, ErrUnsupportedFeature
+	}
+
+	return &AmbientLightSensor{device: d, mgr: m}, nil
+}
+
+func (m *DeviceManager) MotionSensor(d *devices.Device) (*MotionSensor, error) {
+	if !m.Supports(d, FeatureMotionSensor) {
+		return nil, ErrUnsupportedFeature
+	}
+
+	return &MotionSensor{device: d, mgr: m}, nil
+}
+
+func (s *MotionSensor) Config() (*MotionConfig, error) {
+	return s.ConfigContext(context.Background())
+}
+
+func (s *MotionSensor) ConfigContext(ctx context.Context) (*MotionConfig, error) {
+	var r MotionSensorConfig
+
+	if err := s.mgr.DoContext(ctx, s.device, &r); err != nil {
+		return nil, err
+	}
+
+	config := r.GetConfig()
+	return &config, nil
+}
+
+func (s *MotionSensor) SetColdTime(coldTime time.Duration) error {
+	return s.SetColdTimeContext(context.Background(), coldTime)
+}
+
+// SetColdTimeContext sets how long the switch stays on after the last
+// motion was detected.
+func (s *MotionSensor) SetColdTimeContext(ctx context.Context, coldTime time.Duration) error {
+	if coldTime < time.Second || coldTime%time.Millisecond != 0 {
+		return fmt.Errorf("%w: cold time %s", ErrInvalidParameter, coldTime)
+	}
+
+	var r MotionSensorColdTime
+	r.SetColdTime(int(coldTime.Milliseconds()))
+
+	return s.mgr.DoContext(ctx, s.device, &r)
+}
+
+func (s *MotionSensor) SetCustomSensitivity(value int) error {
+	return s.SetCustomSensitivityContext(context.Background(), value)
+}
+
+// SetCustomSensitivityContext selects MotionRangeCustom with a sensitivity
+// between 0 and 100.
+func (s *MotionSensor) SetCustomSensitivityContext(ctx context.Context, value int) error {
+	if value < 0 || value > 100 {
+		return fmt.Errorf("%w: sensitivity %d", ErrInvalidParameter, value)
+	}
+
+	var r MotionSensorSensitivity
+	r.SetCustom(int(MotionRangeCustom), value)
+
+	return s.mgr.DoContext(ctx, s.device, &r)
+}
+
+func (s *MotionSensor) SetEnabled(enabled bool) error {
+	return s.SetEnabledContext(context.Background(), enabled)
+}
+
+func (s *MotionSensor) SetEnabledContext(ctx context.Context, enabled bool) error {
+	var r MotionSensorEnable
+	r.SetEnabled(enabled)
+
+	return s.mgr.DoContext(ctx, s.device, &r)
+}
+
+func (s *MotionSensor) SetRange(r MotionRange) error {
+	return s.SetRangeContext(context.Background(), r)
+}
+
+// SetRangeContext selects one of the preset ranges. Use
+// SetCustomSensitivity for MotionRangeCustom.
+func (s *MotionSensor) SetRangeContext(ctx context.Context, r MotionRange) error {
+	if r < MotionRangeFar || r > MotionRangeNear {
+		return fmt.Errorf("%w: motion range %d", ErrInvalidParameter, r)
+	}
+
+	var cmd MotionSensorSensitivity
+	cmd.SetRange(int(r))
+
+	return s.mgr.DoContext(ctx, s.device, &cmd)
+}
+
+func (s *AmbientLightSensor) Brightness() (int, error) {
+	return s.BrightnessContext(context.Background())
+}
+
+// BrightnessContext returns the current ambient brightness as a percentage.
+func (s *AmbientLightSensor) BrightnessContext(ctx context.Context) (int, error) {
+	var r AmbientLightSensorBrightness
+
+	if err := s.mgr.DoContext(ctx, s.device, &r); err != nil {
+		return 0, err
+	}
+
+	return r.GetBrightness(), nil
+}
+
+func (s *AmbientLightSensor) Config() (*AmbientLightConfig, error) {
+	return s.ConfigContext(context.Background())
+}
+
+func (s *AmbientLightSensor) ConfigContext(ctx context.Context) (*AmbientLightConfig, error) {
+	var r AmbientLightSensorConfig
+
+	if err := s.mgr.DoContext(ctx, s.device, &r); err != nil {
+		return nil, err
+	}
+
+	configs := r.GetConfig()
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("%w: no ambient light sensor", ErrUnsupportedFeature)
+	}
+	return &configs[0], nil
+}
+
+func (s *AmbientLightSensor) SetEnabled(enabled bool) error {
+	return s.SetEnabledContext(context.Background(), enabled)
+}
+
+func (s *AmbientLightSensor) SetEnabledContext(ctx context.Context, enabled bool) error {
+	var r AmbientLightSensorEnable
+	r.SetEnabled(enabled)
+
+	return s.mgr.DoContext(ctx, s.device, &r)
+}
+
+func (s *AmbientLightSensor) SetThreshold(index int, value int) error {
+	return s.SetThresholdContext(context.Background(), index, value)
+}
+
+// SetThresholdContext sets the brightness percentage of the level at index
+// in AmbientLightConfig.Levels.
+func (s *AmbientLightSensor) SetThresholdContext(ctx context.Context, index int, value int) error {
+	if value < 0 || value > 100 {
+		return fmt.Errorf("%w: threshold %d", ErrInvalidParameter, value)
+	}
+
+	config, err := s.ConfigContext(ctx)
+	if err != nil {
+		return err
+	}
+	if index < 0 || index >= len(config.Levels) {
+		return fmt.Errorf("%w: level %d of %d", ErrInvalidParameter, index, len(config.Levels))
+	}
+
+	var r AmbientLightSensorLevel
+	r.SetLevel(index, value)
+
+	return s.mgr.DoContext(ctx, s.device, &r)
+}
@@ -0,0 +1,205 @@
+package tplink
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Aralocke/tplink-smart-go/v1/pkg/devices"
+)
+
+// sensorModules emulates an ES20M switch with motion and ambient light
+// sensors. Settings are kept so they are reflected in get_config.
+func sensorModules() mockModules {
+	pir := map[string]interface{}{
+		"enable":        1,
+		"version":       "1.0",
+		"trigger_index": 1,
+		"cold_time":     60000,
+		"min_adc":       0,
+		"max_adc":       4095,
+		"array":         []interface{}{80.0, 50.0, 20.0, 61.0},
+	}
+	levels := []interface{}{
+		map[string]interface{}{"name": "cloudy", "adc": 490, "value": 20},
+		map[string]interface{}{"name": "overcast", "adc": 294, "value": 12},
+		map[string]interface{}{"name": "custom", "adc": 2400, "value": 97},
+	}
+	las := map[string]interface{}{
+		"hw_id":       0,
+		"enable":      0,
+		"dark_index":  0,
+		"min_adc":     0,
+		"max_adc":     2450,
+		"level_array": levels,
+	}
+
+	modules := plugModules()
+	modules["system"]["get_sysinfo"] = func(map[string]interface{}) interface{} {
+		return map[string]interface{}{
+			"err_code": 0,
+			"alias":    "Hallway",
+			"feature":  "TIM:PIR:LAS",
+			"model":    "ES20M(US)",
+			"mic_type": "IOT.SMARTPLUGSWITCH",
+		}
+	}
+	modules["smartlife.iot.PIR"] = map[string]mockMethod{
+		"get_config": func(map[string]interface{}) interface{} {
+			out := map[string]interface{}{"err_code": 0}
+			for k, v := range pir {
+				out[k] = v
+			}
+			return out
+		},
+		"set_enable": func(params map[string]interface{}) interface{} {
+			pir["enable"] = params["enable"]
+			return map[string]interface{}{"err_code": 0}
+		},
+		"set_cold_time": func(params map[string]interface{}) interface{} {
+			pir["cold_time"] = params["cold_time"]
+			return map[string]interface{}{"err_code": 0}
+		},
+		"set_trigger_sens": func(params map[string]interface{}) interface{} {
+			index := int(params["index"].(float64))
+			pir["trigger_index"] = index
+			if value, ok := params["value"]; ok {
+				pir["array"].([]interface{})[index] = value
+			}
+			return map[string]interface{}{"err_code": 0}
+		},
+	}
+	modules["smartlife.iot.LAS"] = map[string]mockMethod{
+		"get_config": func(map[string]interface{}) interface{} {
+			return map[string]interface{}{
+				"err_code": 0,
+				"ver":      "1.0",
+				"devs":     []interface{}{las},
+			}
+		},
+		"get_current_brt": func(map[string]interface{}) interface{} {
+			return map[string]interface{}{"err_code": 0, "value": 14}
+		},
+		"set_enable": func(params map[string]interface{}) interface{} {
+			las["enable"] = params["enable"]
+			return map[string]interface{}{"err_code": 0}
+		},
+		"set_brt_level": func(params map[string]interface{}) interface{} {
+			index := int(params["index"].(float64))
+			levels[index].(map[string]interface{})["value"] = params["value"]
+			return map[string]interface{}{"err_code": 0}
+		},
+	}
+	return modules
+}
+
+func TestMotionSensor(t *testing.T) {
+	api, _ := newMockManager(sensorModules().handler)
+
+	config := PlugConfig("mock")
+	d, err := api.LoadDevice(&config)
+	if err != nil {
+		t.Fatalf("failed to load device: %s", err)
+	}
+
+	pir, err := api.MotionSensor(d)
+	if err != nil {
+		t.Fatalf("failed to get motion sensor: %s", err)
+	}
+
+	if err = pir.SetEnabled(false); err != nil {
+		t.Fatalf("failed to disable motion sensor: %s", err)
+	}
+	if err = pir.SetColdTime(5 * time.Minute); err != nil {
+		t.Fatalf("failed to set cold time: %s", err)
+	}
+	if err = pir.SetCustomSensitivity(70); err != nil {
+		t.Fatalf("failed to set sensitivity: %s", err)
+	}
+
+	cfg, err := pir.Config()
+	if err != nil {
+		t.Fatalf("failed to get motion config: %s", err)
+	}
+	if cfg.Enabled() || cfg.Cooldown() != 5*time.Minute {
+		t.Fatalf("unexpected motion config %+v", cfg)
+	}
+	if MotionRange(cfg.Range) != MotionRangeCustom || cfg.Sensitivity() != 70 {
+		t.Fatalf("unexpected sensitivity %d (range %d)", cfg.Sensitivity(), cfg.Range)
+	}
+
+	if err = pir.SetRange(MotionRangeNear); err != nil {
+		t.Fatalf("failed to set range: %s", err)
+	}
+	if cfg, _ = pir.Config(); cfg.Sensitivity() != 20 {
+		t.Fatalf("unexpected sensitivity %d", cfg.Sensitivity())
+	}
+
+	if err = pir.SetRange(MotionRangeCustom); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = pir.SetCustomSensitivity(101); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = pir.SetColdTime(500 * time.Millisecond); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestAmbientLightSensor(t *testing.T) {
+	api, _ := newMockManager(sensorModules().handler)
+
+	config := PlugConfig("mock")
+	d, err := api.LoadDevice(&config)
+	if err != nil {
+		t.Fatalf("failed to load device: %s", err)
+	}
+
+	las, err := api.AmbientLightSensor(d)
+	if err != nil {
+		t.Fatalf("failed to get ambient light sensor: %s", err)
+	}
+
+	if brightness, err := las.Brightness(); err != nil || brightness != 14 {
+		t.Fatalf("unexpected brightness %d: %v", brightness, err)
+	}
+
+	if err = las.SetEnabled(true); err != nil {
+		t.Fatalf("failed to enable ambient light sensor: %s", err)
+	}
+	if err = las.SetThreshold(2, 50); err != nil {
+		t.Fatalf("failed to set threshold: %s", err)
+	}
+
+	cfg, err := las.Config()
+	if err != nil {
+		t.Fatalf("failed to get ambient light config: %s", err)
+	}
+	if !cfg.Enabled() || len(cfg.Levels) != 3 || cfg.Levels[2].Value != 50 || cfg.Levels[2].Name != "custom" {
+		t.Fatalf("unexpected ambient light config %+v", cfg)
+	}
+
+	if err = las.SetThreshold(3, 50); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = las.SetThreshold(0, -1); !errors.Is(err, ErrInvalidParameter) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSensorsUnsupported(t *testing.T) {
+	api, _ := newMockManager(plugHandler)
+
+	config := PlugConfig("mock")
+	d, err := api.LoadDevice(&config)
+	if err != nil {
+		t.Fatalf("failed to load device: %s", err)
+	}
+
+	if _, err = api.MotionSensor(d); !errors.Is(err, ErrUnsupportedFeature) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = api.AmbientLightSensor(devices.NewDevice(&config)); !errors.Is(err, ErrUnsupportedFeature) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
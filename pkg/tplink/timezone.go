@@ -0,0 +1,198 @@
+package tplink
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	// The zone database is embedded so the timezone table can be used on
+	// hosts without zoneinfo, such as minimal containers.
+	_ "time/tzdata"
+)
+
+var ErrUnknownTimezone = errors.New("timezone is not known to the device")
+
+// timezones maps the timezone index used by the firmware to an IANA zone
+// with the same offsets and daylight saving rules.
+var timezones = [...]string{
+	"Etc/GMT+12",
+	"Pacific/Samoa",
+	"US/Hawaii",
+	"US/Alaska",
+	"Mexico/BajaNorte",
+	"Etc/GMT+8",
+	"PST8PDT",
+	"US/Arizona",
+	"America/Mazatlan",
+	"MST",
+	"MST7MDT",
+	"Mexico/General",
+	"Etc/GMT+6",
+	"CST6CDT",
+	"America/Monterrey",
+	"Canada/Saskatchewan",
+	"America/Bogota",
+	"Etc/GMT+5",
+	"EST5EDT",
+	"America/Indiana/Indianapolis",
+	"America/Caracas",
+	"America/Asuncion",
+	"Etc/GMT+4",
+	"Canada/Atlantic",
+	"America/Cuiaba",
+	"Brazil/West",
+	"America/Santiago",
+	"Canada/Newfoundland",
+	"America/Sao_Paulo",
+	"America/Argentina/Buenos_Aires",
+	"America/Cayenne",
+	"America/Miquelon",
+	"America/Montevideo",
+	"Chile/Continental",
+	"Etc/GMT+2",
+	"Atlantic/Azores",
+	"Atlantic/Cape_Verde",
+	"Africa/Casablanca",
+	"UCT",
+	"GB",
+	"Africa/Monrovia",
+	"Europe/Amsterdam",
+	"Europe/Belgrade",
+	"Europe/Brussels",
+	"Europe/Sarajevo",
+	"Africa/Lagos",
+	"Africa/Windhoek",
+	"Asia/Amman",
+	"Europe/Athens",
+	"Asia/Beirut",
+	"Africa/Cairo",
+	"Asia/Damascus",
+	"EET",
+	"Africa/Harare",
+	"Europe/Helsinki",
+	"Asia/Istanbul",
+	"Asia/Jerusalem",
+	"Europe/Kaliningrad",
+	"Africa/Tripoli",
+	"Asia/Baghdad",
+	"Asia/Kuwait",
+	"Europe/Minsk",
+	"Europe/Moscow",
+	"Africa/Nairobi",
+	"Asia/Tehran",
+	"Asia/Muscat",
+	"Asia/Baku",
+	"Europe/Samara",
+	"Indian/Mauritius",
+	"Asia/Tbilisi",
+	"Asia/Yerevan",
+	"Asia/Kabul",
+	"Asia/Ashgabat",
+	"Asia/Yekaterinburg",
+	"Asia/Karachi",
+	"Asia/Kolkata",
+	"Asia/Colombo",
+	"Asia/Kathmandu",
+	"Asia/Almaty",
+	"Asia/Dhaka",
+	"Asia/Novosibirsk",
+	"Asia/Rangoon",
+	"Asia/Bangkok",
+	"Asia/Krasnoyarsk",
+	"Asia/Chongqing",
+	"Asia/Irkutsk",
+	"Asia/Singapore",
+	"Australia/Perth",
+	"Asia/Taipei",
+	"Asia/Ulaanbaatar",
+	"Asia/Tokyo",
+	"Asia/Seoul",
+	"Asia/Yakutsk",
+	"Australia/Adelaide",
+	"Australia/Darwin",
+	"Australia/Brisbane",
+	"Australia/Canberra",
+	"Pacific/Guam",
+	"Australia/Hobart",
+	"Antarctica/DumontDUrville",
+	"Asia/Magadan",
+	"Asia/Srednekolymsk",
+	"Etc/GMT-11",
+	"Asia/Anadyr",
+	"Pacific/Auckland",
+	"Etc/GMT-12",
+	"Pacific/Fiji",
+	"Etc/GMT-13",
+	"Pacific/Apia",
+	"Etc/GMT-14",
+}
+
+// TimezoneLocation returns the location of a firmware timezone index.
+func TimezoneLocation(index int) (*time.Location, error) {
+	if index < 0 || index >= len(timezones) {
+		return nil, fmt.Errorf("%w: index %d", ErrUnknownTimezone, index)
+	}
+
+	loc, err := time.LoadLocation(timezones[index])
+	if err != nil {
+		return nil, fmt.Errorf("%w: zone %s of index %d: %w", ErrUnknownTimezone, timezones[index], index, err)
+	}
+	return loc, nil
+}
+
+// TimezoneIndex returns the firmware timezone index for an IANA zone name.
+// Zones missing from the firmware table are matched by their offsets over
+// the current year, so daylight saving time changes on the same days. When
+// no zone follows the same rules the first zone with the current offset is
+// used.
+func TimezoneIndex(zone string) (int, error) {
+	for index, name := range timezones {
+		if name == zone {
+			return index, nil
+		}
+	}
+
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrUnknownTimezone, err)
+	}
+
+	now := time.Now()
+	start := time.Date(now.Year(), time.January, 1, 12, 0, 0, 0, time.UTC)
+	fallback := -1
+
+	for index, name := range timezones {
+		candidate, err := time.LoadLocation(name)
+		if err != nil {
+			continue
+		}
+
+		if fallback < 0 && offset(now, loc) == offset(now, candidate) {
+			fallback = index
+		}
+		if sameOffsets(start, loc, candidate) {
+			return index, nil
+		}
+	}
+
+	if fallback < 0 {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownTimezone, zone)
+	}
+	return fallback, nil
+}
+
+func offset(t time.Time, loc *time.Location) int {
+	_, off := t.In(loc).Zone()
+	return off
+}
+
+// sameOffsets compares both locations once a day for a year.
+func sameOffsets(start time.Time, a *time.Location, b *time.Location) bool {
+	for day := 0; day < 366; day++ {
+		t := start.AddDate(0, 0, day)
+		if offset(t, a) != offset(t, b) {
+			return false
+		}
+	}
+	return true
+}
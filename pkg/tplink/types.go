@@ -1,6 +1,9 @@
 package tplink
 
-import "math"
+import (
+	"encoding/json"
+	"math"
+)
 
 // Basic Instructions
 
@@ -20,7 +23,8 @@ type deviceIdValue struct {
 }
 
 type errorCode struct {
-	ErrorCode int `json:"err_code,omitempty"`
+	ErrorCode    int    `json:"err_code,omitempty"`
+	ErrorMessage string `json:"err_msg,omitempty"`
 }
 
 type hardwareIdValue struct {
@@ -53,12 +57,59 @@ type stateValue struct {
 
 // EnergyMeter Types
 
+// RealTimeEnergy holds the readings in A, V, W and kWh. Firmware of newer
+// hardware revisions reports 'current_ma', 'voltage_mv', 'power_mw' and
+// 'total_wh' instead, which are converted when decoding. The Has flags tell
+// a reading of zero apart from one the firmware did not report.
 type RealTimeEnergy struct {
 	errorCode
-	Current float32 `json:"current,omitempty"`
-	Voltage float32 `json:"voltage,omitempty"`
-	Power   float32 `json:"power,omitempty"`
-	Total   float32 `json:"total,omitempty"`
+	Current float64 `json:"current,omitempty"`
+	Voltage float64 `json:"voltage,omitempty"`
+	Power   float64 `json:"power,omitempty"`
+	Total   float64 `json:"total,omitempty"`
+
+	HasCurrent bool `json:"-"`
+	HasVoltage bool `json:"-"`
+	HasPower   bool `json:"-"`
+	HasTotal   bool `json:"-"`
+}
+
+func (r *RealTimeEnergy) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		errorCode
+		Current   *float64 `json:"current"`
+		CurrentMa *float64 `json:"current_ma"`
+		Voltage   *float64 `json:"voltage"`
+		VoltageMv *float64 `json:"voltage_mv"`
+		Power     *float64 `json:"power"`
+		PowerMw   *float64 `json:"power_mw"`
+		Total     *float64 `json:"total"`
+		TotalWh   *float64 `json:"total_wh"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*r = RealTimeEnergy{errorCode: raw.errorCode}
+	r.Current, r.HasCurrent = energyValue(raw.Current, raw.CurrentMa)
+	r.Voltage, r.HasVoltage = energyValue(raw.Voltage, raw.VoltageMv)
+	r.Power, r.HasPower = energyValue(raw.Power, raw.PowerMw)
+	r.Total, r.HasTotal = energyValue(raw.Total, raw.TotalWh)
+
+	return nil
+}
+
+// energyValue prefers the reading in the base unit over the one reported in
+// thousandths of it.
+func energyValue(value *float64, milli *float64) (float64, bool) {
+	if value != nil {
+		return *value, true
+	}
+	if milli != nil {
+		return *milli / 1000, true
+	}
+	return 0, false
 }
 
 type GetRealTimeEnergy struct {
@@ -69,10 +120,85 @@ type ElectricityMeterInfo struct {
 	EMeter GetRealTimeEnergy `json:"emeter"`
 }
 
+func (e *ElectricityMeterInfo) ErrorCode() int {
+	return e.EMeter.Energy.ErrorCode
+}
+
+func (e *ElectricityMeterInfo) Method() string {
+	return "get_realtime"
+}
+
+func (e *ElectricityMeterInfo) Module() string {
+	return "emeter"
+}
+
 func (e *ElectricityMeterInfo) Realtime() *RealTimeEnergy {
 	return &e.EMeter.Energy
 }
 
+// Calibration
+// SEND {"emeter":{"get_vgain_igain":{}}}
+// RECV {"emeter":{"get_vgain_igain":{"vgain":13462,"igain":16835,"err_code":0}}}
+
+// Gains are the calibration factors of the voltage and current readings.
+type Gains struct {
+	VGain int `json:"vgain,omitempty"`
+	IGain int `json:"igain,omitempty"`
+}
+
+type gainValues struct {
+	errorCode
+	Gains
+}
+
+type getGains struct {
+	Gains gainValues `json:"get_vgain_igain"`
+}
+
+type EMeterGains struct {
+	EMeter getGains `json:"emeter"`
+}
+
+func (e *EMeterGains) ErrorCode() int {
+	return e.EMeter.Gains.ErrorCode
+}
+
+func (e *EMeterGains) Method() string {
+	return "get_vgain_igain"
+}
+
+func (e *EMeterGains) Module() string {
+	return "emeter"
+}
+
+func (e *EMeterGains) GetGains() Gains {
+	return e.EMeter.Gains.Gains
+}
+
+type setGains struct {
+	Gains gainValues `json:"set_vgain_igain"`
+}
+
+type EMeterSetGains struct {
+	EMeter setGains `json:"emeter"`
+}
+
+func (e *EMeterSetGains) ErrorCode() int {
+	return e.EMeter.Gains.ErrorCode
+}
+
+func (e *EMeterSetGains) Method() string {
+	return "set_vgain_igain"
+}
+
+func (e *EMeterSetGains) Module() string {
+	return "emeter"
+}
+
+func (e *EMeterSetGains) SetGains(g Gains) {
+	e.EMeter.Gains.Gains = g
+}
+
 // Daily Average
 // SEND {"emeter": {"get_daystat": {"month": 11, "year": 2021}}}
 // RECV {"emeter":{"get_daystat":{"day_list":[{"year":2021,"month":11,"day":1,"energy":1.172000},{"year":2021,"month":11,"day":2,"energy":1.170000},{"year":2021,"month":11,"day":3,"energy":1.128000},{"year":2021,"month":11,"day":4,"energy":1.148000},{"year":2021,"month":11,"day":5,"energy":1.171000},{"year":2021,"month":11,"day":6,"energy":1.169000},{"year":2021,"month":11,"day":7,"energy":1.166000},{"year":2021,"month":11,"day":8,"energy":1.163000},{"year":2021,"month":11,"day":9,"energy":1.159000},{"year":2021,"month":11,"day":10,"energy":1.126000},{"year":2021,"month":11,"day":11,"energy":1.130000},{"year":2021,"month":11,"day":12,"energy":1.133000},{"year":2021,"month":11,"day":13,"energy":1.131000},{"year":2021,"month":11,"day":14,"energy":0.899000}],"err_code":0}}}
@@ -83,6 +209,8 @@ func (e *ElectricityMeterInfo) Realtime() *RealTimeEnergy {
 // RECV {"emeter":{"get_daystat":{"day_list":[{"year":2021,"month":11,"day":1,"energy":1.172000},{"year":2021,"month":11,"day":2,"energy":1.170000},{"year":2021,"month":11,"day":3,"energy":1.128000},{"year":2021,"month":11,"day":4,"energy":1.148000},{"year":2021,"month":11,"day":5,"energy":1.171000},{"year":2021,"month":11,"day":6,"energy":1.169000},{"year":2021,"month":11,"day":7,"energy":1.166000},{"year":2021,"month":11,"day":8,"energy":1.163000},{"year":2021,"month":11,"day":9,"energy":1.159000},{"year":2021,"month":11,"day":10,"energy":1.126000},{"year":2021,"month":11,"day":11,"energy":1.130000},{"year":2021,"month":11,"day":12,"energy":1.133000},{"year":2021,"month":11,"day":13,"energy":1.131000},{"year":2021,"month":11,"day":14,"energy":0.900000}],"err_code":0}}}
 // [{'year': 2021, 'month': 11, 'day': 1, 'energy': 1.172}, {'year': 2021, 'month': 11, 'day': 2, 'energy': 1.17}, {'year': 2021, 'month': 11, 'day': 3, 'energy': 1.128}, {'year': 2021, 'month': 11, 'day': 4, 'energy': 1.148}, {'year': 2021, 'month': 11, 'day': 5, 'energy': 1.171}, {'year': 2021, 'month': 11, 'day': 6, 'energy': 1.169}, {'year': 2021, 'month': 11, 'day': 7, 'energy': 1.166}, {'year': 2021, 'month': 11, 'day': 8, 'energy': 1.163}, {'year': 2021, 'month': 11, 'day': 9, 'energy': 1.159}, {'year': 2021, 'month': 11, 'day': 10, 'energy': 1.126}, {'year': 2021, 'month': 11, 'day': 11, 'energy': 1.13}, {'year': 2021, 'month': 11, 'day': 12, 'energy': 1.133}, {'year': 2021, 'month': 11, 'day': 13, 'energy': 1.131}, {'year': 2021, 'month': 11, 'day': 14, 'energy': 0.9}]
 
+// DayStat is the energy in kWh used on a day, or in a month for monthly
+// statistics. 'energy_wh' reported by newer firmware is converted.
 type DayStat struct {
 	Day    int     `json:"day,omitempty"`
 	Energy float32 `json:"energy,omitempty"`
@@ -90,6 +218,25 @@ type DayStat struct {
 	Year   int     `json:"year,omitempty"`
 }
 
+func (d *DayStat) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Day      int      `json:"day"`
+		Energy   *float64 `json:"energy"`
+		EnergyWh *float64 `json:"energy_wh"`
+		Month    int      `json:"month"`
+		Year     int      `json:"year"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	energy, _ := energyValue(raw.Energy, raw.EnergyWh)
+	*d = DayStat{Day: raw.Day, Energy: float32(energy), Month: raw.Month, Year: raw.Year}
+
+	return nil
+}
+
 type DailyStats struct {
 	DayList []DayStat `json:"day_list,omitempty"`
 	errorCode
@@ -105,10 +252,27 @@ type EMeterDailyStats struct {
 	EMeter getDailyStat `json:"emeter"`
 }
 
+func (e *EMeterDailyStats) ErrorCode() int {
+	return e.EMeter.Stats.ErrorCode
+}
+
+func (e *EMeterDailyStats) Method() string {
+	return "get_daystat"
+}
+
+func (e *EMeterDailyStats) Module() string {
+	return "emeter"
+}
+
 func (e *EMeterDailyStats) DailyStats() *DailyStats {
 	return &e.EMeter.Stats
 }
 
+func (e *EMeterDailyStats) SetMonth(year int, month int) {
+	e.EMeter.Stats.Year = year
+	e.EMeter.Stats.Month = month
+}
+
 // Monthly Average
 // SEND {"emeter": {"get_daystat": {"month": 11, "year": 2021}}}
 // RECV {"emeter":{"get_daystat":{"day_list":[{"year":2021,"month":11,"day":1,"energy":1.172000},{"year":2021,"month":11,"day":2,"energy":1.170000},{"year":2021,"month":11,"day":3,"energy":1.128000},{"year":2021,"month":11,"day":4,"energy":1.148000},{"year":2021,"month":11,"day":5,"energy":1.171000},{"year":2021,"month":11,"day":6,"energy":1.169000},{"year":2021,"month":11,"day":7,"energy":1.166000},{"year":2021,"month":11,"day":8,"energy":1.163000},{"year":2021,"month":11,"day":9,"energy":1.159000},{"year":2021,"month":11,"day":10,"energy":1.126000},{"year":2021,"month":11,"day":11,"energy":1.130000},{"year":2021,"month":11,"day":12,"energy":1.133000},{"year":2021,"month":11,"day":13,"energy":1.131000},{"year":2021,"month":11,"day":14,"energy":0.900000}],"err_code":0}}}
@@ -133,15 +297,51 @@ type EMeterMonthlyStats struct {
 	EMeter getMonthStat `json:"emeter"`
 }
 
+func (e *EMeterMonthlyStats) ErrorCode() int {
+	return e.EMeter.Stats.ErrorCode
+}
+
+func (e *EMeterMonthlyStats) Method() string {
+	return "get_monthstat"
+}
+
+func (e *EMeterMonthlyStats) Module() string {
+	return "emeter"
+}
+
 func (e *EMeterMonthlyStats) MonthlyStats() *MonthlyStats {
 	return &e.EMeter.Stats
 }
 
+func (e *EMeterMonthlyStats) SetYear(year int) {
+	e.EMeter.Stats.Year = year
+}
+
 // Clear Usage Stats
 // SEND {"emeter": {"erase_emeter_stat": {}}}
 // RECV {"emeter":{"erase_emeter_stat":{"err_code":0}}}
 // {'emeter': {'erase_emeter_stat': {'err_code': 0}}}
 
+type eraseStats struct {
+	Erase errorCode `json:"erase_emeter_stat"`
+}
+
+type EMeterEraseStats struct {
+	EMeter eraseStats `json:"emeter"`
+}
+
+func (e *EMeterEraseStats) ErrorCode() int {
+	return e.EMeter.Erase.ErrorCode
+}
+
+func (e *EMeterEraseStats) Method() string {
+	return "erase_emeter_stat"
+}
+
+func (e *EMeterEraseStats) Module() string {
+	return "emeter"
+}
+
 // SystemInfo Types
 
 type SystemInfo struct {
@@ -149,6 +349,7 @@ type SystemInfo struct {
 	SoftwareVersion string  `json:"sw_ver,omitempty"`
 	HardwareVersion string  `json:"hw_ver,omitempty"`
 	Type            string  `json:"type,omitempty"`
+	MicType         string  `json:"mic_type,omitempty"`
 	Model           string  `json:"model,omitempty"`
 	MacAddress      string  `json:"mac,omitempty"`
 	DeviceId        string  `json:"deviceId,omitempty"`
@@ -167,6 +368,40 @@ type SystemInfo struct {
 	LedStatus       int     `json:"led_off,omitempty"`
 	Latitude        float32 `json:"latitude,omitempty"`
 	Longitude       float32 `json:"longitude,omitempty"`
+
+	// Power strips only
+	ChildNum int         `json:"child_num,omitempty"`
+	Children []ChildInfo `json:"children,omitempty"`
+
+	// Dimmers only
+	Brightness *int `json:"brightness,omitempty"`
+
+	// Bulbs only
+	IsDimmable          int         `json:"is_dimmable,omitempty"`
+	IsColor             int         `json:"is_color,omitempty"`
+	IsVariableColorTemp int         `json:"is_variable_color_temp,omitempty"`
+	LightState          *LightState `json:"light_state,omitempty"`
+
+	// Light strips only
+	Length         int                  `json:"length,omitempty"`
+	LightingEffect *LightingEffectState `json:"lighting_effect_state,omitempty"`
+}
+
+// LightingEffectState is the effect a light strip is playing, if enabled.
+type LightingEffectState struct {
+	Enable     int    `json:"enable"`
+	Name       string `json:"name,omitempty"`
+	Brightness int    `json:"brightness,omitempty"`
+	Custom     int    `json:"custom"`
+	Id         string `json:"id,omitempty"`
+}
+
+// ChildInfo describes an outlet of a power strip.
+type ChildInfo struct {
+	Id         string `json:"id"`
+	Alias      string `json:"alias,omitempty"`
+	RelayState int    `json:"state"`
+	OnTime     int    `json:"on_time,omitempty"`
 }
 
 type GetSystemInfo struct {
@@ -177,6 +412,18 @@ type DeviceInfo struct {
 	System GetSystemInfo `json:"system"`
 }
 
+func (d *DeviceInfo) ErrorCode() int {
+	return d.System.Info.ErrorCode
+}
+
+func (d *DeviceInfo) Method() string {
+	return "get_sysinfo"
+}
+
+func (d *DeviceInfo) Module() string {
+	return "system"
+}
+
 func (d *DeviceInfo) SystemInfo() *SystemInfo {
 	return &d.System.Info
 }
@@ -195,6 +442,14 @@ func (n *NetworkSettings) ErrorCode() int {
     return n.Interface.Settings.ErrorCode
 }
 
+func (n *NetworkSettings) Method() string {
+	return "set_stainfo"
+}
+
+func (n *NetworkSettings) Module() string {
+	return "netif"
+}
+
 func (n *NetworkSettings) GetNetwork() string {
 	return n.Interface.Settings.NetworkName
 }
@@ -203,12 +458,77 @@ func (n *NetworkSettings) GetPassword() string {
 	return n.Interface.Settings.Password
 }
 
+func (n *NetworkSettings) GetKeyType() int {
+	return n.Interface.Settings.KeyType
+}
+
+func (n *NetworkSettings) SetKeyType(keyType int) {
+	n.Interface.Settings.KeyType = keyType
+}
+
 func (n *NetworkSettings) SetSettings(network string, password string) {
-	n.Interface.Settings.KeyType = 3 // WPA2
+	n.Interface.Settings.KeyType = KeyTypeWPA2
 	n.Interface.Settings.NetworkName = network
 	n.Interface.Settings.Password = password
 }
 
+// Wireless Scan
+// SEND {"netif":{"get_scaninfo":{"refresh":1}}}
+// RECV {"netif":{"get_scaninfo":{"ap_list":[{"ssid":"Home","key_type":3}],"err_code":0}}}
+
+// Key types of wireless networks as used by 'key_type'.
+const (
+	KeyTypeNone = 0
+	KeyTypeWEP  = 1
+	KeyTypeWPA  = 2
+	KeyTypeWPA2 = 3
+)
+
+type AccessPoint struct {
+	SSID    string `json:"ssid"`
+	KeyType int    `json:"key_type"`
+}
+
+type scanValues struct {
+	errorCode
+	Refresh     int           `json:"refresh,omitempty"`
+	AccessPoint []AccessPoint `json:"ap_list,omitempty"`
+}
+
+type networkScan struct {
+	Scan scanValues `json:"get_scaninfo"`
+}
+
+type NetworkScan struct {
+	Interface networkScan `json:"netif"`
+}
+
+func (n *NetworkScan) ErrorCode() int {
+	return n.Interface.Scan.ErrorCode
+}
+
+func (n *NetworkScan) Method() string {
+	return "get_scaninfo"
+}
+
+func (n *NetworkScan) Module() string {
+	return "netif"
+}
+
+func (n *NetworkScan) GetAccessPoints() []AccessPoint {
+	return n.Interface.Scan.AccessPoint
+}
+
+// SetRefresh makes the device scan again instead of returning the result
+// of its last scan.
+func (n *NetworkScan) SetRefresh(refresh bool) {
+	if refresh {
+		n.Interface.Scan.Refresh = 1
+	} else {
+		n.Interface.Scan.Refresh = 0
+	}
+}
+
 // System Alias
 
 type systemAlias struct {
@@ -223,6 +543,14 @@ func (a *SystemAlias) ErrorCode() int {
     return a.System.Alias.ErrorCode
 }
 
+func (a *SystemAlias) Method() string {
+	return "set_dev_alias"
+}
+
+func (a *SystemAlias) Module() string {
+	return "system"
+}
+
 func (a *SystemAlias) GetAlias() string {
 	return a.System.Alias.Value
 }
@@ -245,6 +573,14 @@ func (d *SystemDeviceId) ErrorCode() int {
     return d.System.DeviceId.ErrorCode
 }
 
+func (d *SystemDeviceId) Method() string {
+	return "set_device_id"
+}
+
+func (d *SystemDeviceId) Module() string {
+	return "system"
+}
+
 func (d *SystemDeviceId) GetDeviceId() string {
 	return d.System.DeviceId.Value
 }
@@ -267,6 +603,14 @@ func (d *SystemHardwareId) ErrorCode() int {
     return d.System.HardwareId.ErrorCode
 }
 
+func (d *SystemHardwareId) Method() string {
+	return "set_hw_id"
+}
+
+func (d *SystemHardwareId) Module() string {
+	return "system"
+}
+
 func (d *SystemHardwareId) GetHardwareId() string {
 	return d.System.HardwareId.Value
 }
@@ -289,6 +633,14 @@ func (s *SystemLedState) ErrorCode() int {
     return s.System.LedState.ErrorCode
 }
 
+func (s *SystemLedState) Method() string {
+	return "set_led_off"
+}
+
+func (s *SystemLedState) Module() string {
+	return "system"
+}
+
 func (s *SystemLedState) GetState() bool {
 	return s.System.LedState.Value != 0
 }
@@ -315,6 +667,14 @@ func (loc *SystemLocation) ErrorCode() int {
     return loc.System.Location.ErrorCode
 }
 
+func (loc *SystemLocation) Method() string {
+	return "set_dev_location"
+}
+
+func (loc *SystemLocation) Module() string {
+	return "system"
+}
+
 func (loc *SystemLocation) GetLatitude() float64 {
 	lat := loc.System.Location.Latitude
 	return math.Floor(lat*1000) / 1000
@@ -330,6 +690,117 @@ func (loc *SystemLocation) SetLocation(lat float64, lon float64) {
 	loc.System.Location.Longitude = lon
 }
 
+// Firmware Upgrade
+// SEND {"system":{"download_firmware":{"url":"http://download.tplinkcloud.com/firmware/hs110v1_1.2.6.bin"}}}
+// SEND {"system":{"get_download_state":{}}}
+// RECV {"system":{"get_download_state":{"status":1,"ratio":42,"reboot_time":10,"flash_time":30,"err_code":0}}}
+// SEND {"system":{"flash_firmware":{}}}
+
+type urlValue struct {
+	errorCode
+	URL string `json:"url"`
+}
+
+type downloadFirmware struct {
+	Download urlValue `json:"download_firmware"`
+}
+
+type SystemDownloadFirmware struct {
+	System downloadFirmware `json:"system"`
+}
+
+func (f *SystemDownloadFirmware) ErrorCode() int {
+	return f.System.Download.ErrorCode
+}
+
+func (f *SystemDownloadFirmware) Method() string {
+	return "download_firmware"
+}
+
+func (f *SystemDownloadFirmware) Module() string {
+	return "system"
+}
+
+func (f *SystemDownloadFirmware) GetURL() string {
+	return f.System.Download.URL
+}
+
+func (f *SystemDownloadFirmware) SetURL(url string) {
+	f.System.Download.URL = url
+}
+
+const (
+	DownloadStatusIdle        = 0
+	DownloadStatusDownloading = 1
+	DownloadStatusDownloaded  = 2
+	DownloadStatusFailed      = 3
+)
+
+// DownloadState reports the progress of a firmware download. Ratio is the
+// downloaded percentage, RebootTime and FlashTime are the seconds the device
+// needs to flash the firmware and to come back afterwards.
+type DownloadState struct {
+	errorCode
+	Status     int `json:"status,omitempty"`
+	Ratio      int `json:"ratio,omitempty"`
+	RebootTime int `json:"reboot_time,omitempty"`
+	FlashTime  int `json:"flash_time,omitempty"`
+}
+
+func (s *DownloadState) Complete() bool {
+	return !s.Failed() && s.Ratio >= 100
+}
+
+// Failed reports a download the device gave up on, for example because the
+// server could not be reached. Some firmware reports a negative status.
+func (s *DownloadState) Failed() bool {
+	return s.Status == DownloadStatusFailed || s.Status < 0
+}
+
+type downloadState struct {
+	State DownloadState `json:"get_download_state"`
+}
+
+type SystemDownloadState struct {
+	System downloadState `json:"system"`
+}
+
+func (f *SystemDownloadState) ErrorCode() int {
+	return f.System.State.ErrorCode
+}
+
+func (f *SystemDownloadState) Method() string {
+	return "get_download_state"
+}
+
+func (f *SystemDownloadState) Module() string {
+	return "system"
+}
+
+func (f *SystemDownloadState) DownloadState() *DownloadState {
+	return &f.System.State
+}
+
+type flashFirmware struct {
+	Flash errorCode `json:"flash_firmware"`
+}
+
+type SystemFlashFirmware struct {
+	System flashFirmware `json:"system"`
+}
+
+func (f *SystemFlashFirmware) ErrorCode() int {
+	return f.System.Flash.ErrorCode
+}
+
+func (f *SystemFlashFirmware) Method() string {
+	return "flash_firmware"
+}
+
+func (f *SystemFlashFirmware) Module() string {
+	return "system"
+}
+
 // Reboot Command
 
 type reboot struct {
@@ -344,6 +815,14 @@ func (r *SystemReboot) ErrorCode() int {
 	return r.System.Reboot.ErrorCode
 }
 
+func (r *SystemReboot) Method() string {
+	return "reboot"
+}
+
+func (r *SystemReboot) Module() string {
+	return "system"
+}
+
 func (r *SystemReboot) GetDelay() int {
 	return r.System.Reboot.Delay
 }
@@ -366,6 +845,14 @@ func (r *SystemRelayState) ErrorCode() int {
 	return r.System.RelayState.ErrorCode
 }
 
+func (r *SystemRelayState) Method() string {
+	return "set_relay_state"
+}
+
+func (r *SystemRelayState) Module() string {
+	return "system"
+}
+
 func (r *SystemRelayState) GetRelayState() bool {
 	return r.System.RelayState.State != 0
 }
@@ -392,6 +879,14 @@ func (r *SystemReset) ErrorCode() int {
 	return r.System.Reset.ErrorCode
 }
 
+func (r *SystemReset) Method() string {
+	return "reset"
+}
+
+func (r *SystemReset) Module() string {
+	return "system"
+}
+
 func (r *SystemReset) GetDelay() int {
 	return r.System.Reset.Delay
 }
@@ -399,3 +894,1056 @@ func (r *SystemReset) GetDelay() int {
 func (r *SystemReset) SetDelay(d int) {
 	r.System.Reset.Delay = d
 }
+
+// Bulb Lighting Service
+// SEND {"smartlife.iot.smartbulb.lightingservice":{"get_light_state":{}}}
+// RECV {"smartlife.iot.smartbulb.lightingservice":{"get_light_state":{"on_off":1,"mode":"normal","hue":0,"saturation":0,"color_temp":2700,"brightness":100,"err_code":0}}}
+// SEND {"smartlife.iot.smartbulb.lightingservice":{"transition_light_state":{"on_off":1,"brightness":50,"transition_period":1000,"ignore_default":1}}}
+
+const lightingService = "smartlife.iot.smartbulb.lightingservice"
+
+type LightState struct {
+	errorCode
+	OnOff      int    `json:"on_off"`
+	Mode       string `json:"mode,omitempty"`
+	Hue        int    `json:"hue"`
+	Saturation int    `json:"saturation"`
+	ColorTemp  int    `json:"color_temp"`
+	Brightness int    `json:"brightness"`
+
+	// DefaultOnState is reported instead of the above while the bulb is off,
+	// it is the state the bulb returns to when turned on.
+	DefaultOnState *LightState `json:"dft_on_state,omitempty"`
+}
+
+func (l *LightState) IsOn() bool {
+	return l.OnOff != 0
+}
+
+type getLightState struct {
+	State LightState `json:"get_light_state"`
+}
+
+type BulbLightState struct {
+	Service getLightState `json:"smartlife.iot.smartbulb.lightingservice"`
+}
+
+func (b *BulbLightState) ErrorCode() int {
+	return b.Service.State.ErrorCode
+}
+
+func (b *BulbLightState) LightState() *LightState {
+	return &b.Service.State
+}
+
+func (b *BulbLightState) Method() string {
+	return "get_light_state"
+}
+
+func (b *BulbLightState) Module() string {
+	return lightingService
+}
+
+// LightTransition describes a change of the light state. Fields left nil
+// are not sent and keep their current value on the bulb.
+type LightTransition struct {
+	errorCode
+	OnOff            *int   `json:"on_off,omitempty"`
+	Mode             string `json:"mode,omitempty"`
+	Hue              *int   `json:"hue,omitempty"`
+	Saturation       *int   `json:"saturation,omitempty"`
+	ColorTemp        *int   `json:"color_temp,omitempty"`
+	Brightness       *int   `json:"brightness,omitempty"`
+	TransitionPeriod int    `json:"transition_period,omitempty"`
+	IgnoreDefault    int    `json:"ignore_default,omitempty"`
+}
+
+type transitionLightState struct {
+	Transition LightTransition `json:"transition_light_state"`
+}
+
+type BulbTransition struct {
+	Service transitionLightState `json:"smartlife.iot.smartbulb.lightingservice"`
+}
+
+func (b *BulbTransition) ErrorCode() int {
+	return b.Service.Transition.ErrorCode
+}
+
+func (b *BulbTransition) Method() string {
+	return "transition_light_state"
+}
+
+func (b *BulbTransition) Module() string {
+	return lightingService
+}
+
+func (b *BulbTransition) SetTransition(t LightTransition) {
+	b.Service.Transition = t
+}
+
+type transitionResult struct {
+	State LightState `json:"transition_light_state"`
+}
+
+type bulbTransitionResult struct {
+	Service transitionResult `json:"smartlife.iot.smartbulb.lightingservice"`
+}
+
+// Countdown Rules
+// SEND {"count_down":{"add_rule":{"enable":1,"delay":2700,"act":0,"name":"turn off"}}}
+// RECV {"count_down":{"add_rule":{"id":"7C90311A1CD3227F25C6001D88F7FC13","err_code":0}}}
+
+// CountdownRule switches the relay to Action once Delay seconds have passed.
+// Remaining is reported by the device while the rule is running.
+type CountdownRule struct {
+	Id        string `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Enable    int    `json:"enable"`
+	Delay     int    `json:"delay"`
+	Action    int    `json:"act"`
+	Remaining int    `json:"remain,omitempty"`
+}
+
+type countdownRuleList struct {
+	errorCode
+	Rules []CountdownRule `json:"rule_list,omitempty"`
+}
+
+type countdownRuleValue struct {
+	errorCode
+	CountdownRule
+}
+
+type countdownRules struct {
+	Rules countdownRuleList `json:"get_rules"`
+}
+
+type CountdownRules struct {
+	Countdown countdownRules `json:"count_down"`
+}
+
+func (c *CountdownRules) ErrorCode() int {
+	return c.Countdown.Rules.ErrorCode
+}
+
+func (c *CountdownRules) Method() string {
+	return "get_rules"
+}
+
+func (c *CountdownRules) Module() string {
+	return "count_down"
+}
+
+func (c *CountdownRules) GetRules() []CountdownRule {
+	return c.Countdown.Rules.Rules
+}
+
+type countdownAddRule struct {
+	Rule countdownRuleValue `json:"add_rule"`
+}
+
+type CountdownAddRule struct {
+	Countdown countdownAddRule `json:"count_down"`
+}
+
+func (c *CountdownAddRule) ErrorCode() int {
+	return c.Countdown.Rule.ErrorCode
+}
+
+func (c *CountdownAddRule) Method() string {
+	return "add_rule"
+}
+
+func (c *CountdownAddRule) Module() string {
+	return "count_down"
+}
+
+// GetId returns the id assigned to the rule by the device.
+func (c *CountdownAddRule) GetId() string {
+	return c.Countdown.Rule.Id
+}
+
+func (c *CountdownAddRule) SetRule(rule CountdownRule) {
+	rule.Id = ""
+	rule.Remaining = 0
+	c.Countdown.Rule.CountdownRule = rule
+}
+
+type countdownEditRule struct {
+	Rule countdownRuleValue `json:"edit_rule"`
+}
+
+type CountdownEditRule struct {
+	Countdown countdownEditRule `json:"count_down"`
+}
+
+func (c *CountdownEditRule) ErrorCode() int {
+	return c.Countdown.Rule.ErrorCode
+}
+
+func (c *CountdownEditRule) Method() string {
+	return "edit_rule"
+}
+
+func (c *CountdownEditRule) Module() string {
+	return "count_down"
+}
+
+func (c *CountdownEditRule) SetRule(rule CountdownRule) {
+	rule.Remaining = 0
+	c.Countdown.Rule.CountdownRule = rule
+}
+
+type countdownDeleteRules struct {
+	Delete errorCode `json:"delete_all_rules"`
+}
+
+type CountdownDeleteRules struct {
+	Countdown countdownDeleteRules `json:"count_down"`
+}
+
+func (c *CountdownDeleteRules) ErrorCode() int {
+	return c.Countdown.Delete.ErrorCode
+}
+
+func (c *CountdownDeleteRules) Method() string {
+	return "delete_all_rules"
+}
+
+func (c *CountdownDeleteRules) Module() string {
+	return "count_down"
+}
+
+// Anti Theft (Away Mode)
+// SEND {"anti_theft":{"get_rules":{}}}
+// RECV {"anti_theft":{"get_rules":{"rule_list":[],"enable":0,"version":2,"err_code":0}}}
+
+// AntiTheftRule switches the relay at random between the start and end time
+// to make the home look occupied. Frequency and Duration control how often
+// and for how many minutes the relay is switched on.
+type AntiTheftRule struct {
+	Id        string       `json:"id,omitempty"`
+	Name      string       `json:"name"`
+	Enable    int          `json:"enable"`
+	Days      Weekdays     `json:"wday"`
+	StartOpt  ScheduleTime `json:"stime_opt"`
+	StartMin  int          `json:"smin"`
+	EndOpt    ScheduleTime `json:"etime_opt"`
+	EndMin    int          `json:"emin"`
+	Repeat    int          `json:"repeat"`
+	Frequency int          `json:"frequency"`
+	Duration  int          `json:"duration"`
+	LastFor   int          `json:"lastfor"`
+	Year      int          `json:"year"`
+	Month     int          `json:"month"`
+	Day       int          `json:"day"`
+	Force     int          `json:"force"`
+	Latitude  float32      `json:"latitude"`
+	Longitude float32      `json:"longitude"`
+}
+
+type antiTheftRuleList struct {
+	errorCode
+	Enable  int             `json:"enable,omitempty"`
+	Rules   []AntiTheftRule `json:"rule_list,omitempty"`
+	Version int             `json:"version,omitempty"`
+}
+
+type antiTheftRuleValue struct {
+	errorCode
+	AntiTheftRule
+}
+
+type antiTheftRules struct {
+	Rules antiTheftRuleList `json:"get_rules"`
+}
+
+type AntiTheftRules struct {
+	AntiTheft antiTheftRules `json:"anti_theft"`
+}
+
+func (a *AntiTheftRules) ErrorCode() int {
+	return a.AntiTheft.Rules.ErrorCode
+}
+
+func (a *AntiTheftRules) Method() string {
+	return "get_rules"
+}
+
+func (a *AntiTheftRules) Module() string {
+	return "anti_theft"
+}
+
+func (a *AntiTheftRules) GetEnabled() bool {
+	return a.AntiTheft.Rules.Enable != 0
+}
+
+func (a *AntiTheftRules) GetRules() []AntiTheftRule {
+	return a.AntiTheft.Rules.Rules
+}
+
+type antiTheftAddRule struct {
+	Rule antiTheftRuleValue `json:"add_rule"`
+}
+
+type AntiTheftAddRule struct {
+	AntiTheft antiTheftAddRule `json:"anti_theft"`
+}
+
+func (a *AntiTheftAddRule) ErrorCode() int {
+	return a.AntiTheft.Rule.ErrorCode
+}
+
+func (a *AntiTheftAddRule) Method() string {
+	return "add_rule"
+}
+
+func (a *AntiTheftAddRule) Module() string {
+	return "anti_theft"
+}
+
+// GetId returns the id assigned to the rule by the device.
+func (a *AntiTheftAddRule) GetId() string {
+	return a.AntiTheft.Rule.Id
+}
+
+func (a *AntiTheftAddRule) SetRule(rule AntiTheftRule) {
+	rule.Id = ""
+	a.AntiTheft.Rule.AntiTheftRule = rule
+}
+
+type antiTheftEditRule struct {
+	Rule antiTheftRuleValue `json:"edit_rule"`
+}
+
+type AntiTheftEditRule struct {
+	AntiTheft antiTheftEditRule `json:"anti_theft"`
+}
+
+func (a *AntiTheftEditRule) ErrorCode() int {
+	return a.AntiTheft.Rule.ErrorCode
+}
+
+func (a *AntiTheftEditRule) Method() string {
+	return "edit_rule"
+}
+
+func (a *AntiTheftEditRule) Module() string {
+	return "anti_theft"
+}
+
+func (a *AntiTheftEditRule) SetRule(rule AntiTheftRule) {
+	a.AntiTheft.Rule.AntiTheftRule = rule
+}
+
+type ruleIdValue struct {
+	errorCode
+	Id string `json:"id"`
+}
+
+type antiTheftDeleteRule struct {
+	Rule ruleIdValue `json:"delete_rule"`
+}
+
+type AntiTheftDeleteRule struct {
+	AntiTheft antiTheftDeleteRule `json:"anti_theft"`
+}
+
+func (a *AntiTheftDeleteRule) ErrorCode() int {
+	return a.AntiTheft.Rule.ErrorCode
+}
+
+func (a *AntiTheftDeleteRule) Method() string {
+	return "delete_rule"
+}
+
+func (a *AntiTheftDeleteRule) Module() string {
+	return "anti_theft"
+}
+
+func (a *AntiTheftDeleteRule) SetId(id string) {
+	a.AntiTheft.Rule.Id = id
+}
+
+type antiTheftDeleteRules struct {
+	Delete errorCode `json:"delete_all_rules"`
+}
+
+type AntiTheftDeleteRules struct {
+	AntiTheft antiTheftDeleteRules `json:"anti_theft"`
+}
+
+func (a *AntiTheftDeleteRules) ErrorCode() int {
+	return a.AntiTheft.Delete.ErrorCode
+}
+
+func (a *AntiTheftDeleteRules) Method() string {
+	return "delete_all_rules"
+}
+
+func (a *AntiTheftDeleteRules) Module() string {
+	return "anti_theft"
+}
+
+type enableValue struct {
+	errorCode
+	Enable int `json:"enable"`
+}
+
+type antiTheftEnable struct {
+	Enable enableValue `json:"set_overall_enable"`
+}
+
+type AntiTheftEnable struct {
+	AntiTheft antiTheftEnable `json:"anti_theft"`
+}
+
+func (a *AntiTheftEnable) ErrorCode() int {
+	return a.AntiTheft.Enable.ErrorCode
+}
+
+func (a *AntiTheftEnable) Method() string {
+	return "set_overall_enable"
+}
+
+func (a *AntiTheftEnable) Module() string {
+	return "anti_theft"
+}
+
+func (a *AntiTheftEnable) GetEnabled() bool {
+	return a.AntiTheft.Enable.Enable != 0
+}
+
+func (a *AntiTheftEnable) SetEnabled(enabled bool) {
+	if enabled {
+		a.AntiTheft.Enable.Enable = 1
+	} else {
+		a.AntiTheft.Enable.Enable = 0
+	}
+}
+
+// Motion Sensor
+// SEND {"smartlife.iot.PIR":{"get_config":{}}}
+// RECV {"smartlife.iot.PIR":{"get_config":{"enable":1,"version":"1.0","trigger_index":1,"cold_time":60000,"min_adc":0,"max_adc":4095,"array":[80,50,20,61],"err_code":0}}}
+
+// MotionConfig is the configuration of the motion sensor. Thresholds holds
+// the sensitivity of each MotionRange, ColdTime is in milliseconds.
+type MotionConfig struct {
+	Enable     int    `json:"enable"`
+	Version    string `json:"version,omitempty"`
+	Range      int    `json:"trigger_index"`
+	ColdTime   int    `json:"cold_time"`
+	MinAdc     int    `json:"min_adc"`
+	MaxAdc     int    `json:"max_adc"`
+	Thresholds []int  `json:"array,omitempty"`
+}
+
+type motionConfigValue struct {
+	errorCode
+	MotionConfig
+}
+
+type motionConfig struct {
+	Config motionConfigValue `json:"get_config"`
+}
+
+type MotionSensorConfig struct {
+	PIR motionConfig `json:"smartlife.iot.PIR"`
+}
+
+func (m *MotionSensorConfig) ErrorCode() int {
+	return m.PIR.Config.ErrorCode
+}
+
+func (m *MotionSensorConfig) Method() string {
+	return "get_config"
+}
+
+func (m *MotionSensorConfig) Module() string {
+	return "smartlife.iot.PIR"
+}
+
+func (m *MotionSensorConfig) GetConfig() MotionConfig {
+	return m.PIR.Config.MotionConfig
+}
+
+type motionEnable struct {
+	Enable enableValue `json:"set_enable"`
+}
+
+type MotionSensorEnable struct {
+	PIR motionEnable `json:"smartlife.iot.PIR"`
+}
+
+func (m *MotionSensorEnable) ErrorCode() int {
+	return m.PIR.Enable.ErrorCode
+}
+
+func (m *MotionSensorEnable) Method() string {
+	return "set_enable"
+}
+
+func (m *MotionSensorEnable) Module() string {
+	return "smartlife.iot.PIR"
+}
+
+func (m *MotionSensorEnable) SetEnabled(enabled bool) {
+	if enabled {
+		m.PIR.Enable.Enable = 1
+	} else {
+		m.PIR.Enable.Enable = 0
+	}
+}
+
+// SEND {"smartlife.iot.PIR":{"set_trigger_sens":{"index":3,"value":61}}}
+
+type levelValue struct {
+	errorCode
+	Index int  `json:"index"`
+	Value *int `json:"value,omitempty"`
+}
+
+type motionSensitivity struct {
+	Sensitivity levelValue `json:"set_trigger_sens"`
+}
+
+type MotionSensorSensitivity struct {
+	PIR motionSensitivity `json:"smartlife.iot.PIR"`
+}
+
+func (m *MotionSensorSensitivity) ErrorCode() int {
+	return m.PIR.Sensitivity.ErrorCode
+}
+
+func (m *MotionSensorSensitivity) Method() string {
+	return "set_trigger_sens"
+}
+
+func (m *MotionSensorSensitivity) Module() string {
+	return "smartlife.iot.PIR"
+}
+
+func (m *MotionSensorSensitivity) SetRange(r int) {
+	m.PIR.Sensitivity.Index = r
+	m.PIR.Sensitivity.Value = nil
+}
+
+func (m *MotionSensorSensitivity) SetCustom(r int, value int) {
+	m.PIR.Sensitivity.Index = r
+	m.PIR.Sensitivity.Value = &value
+}
+
+// SEND {"smartlife.iot.PIR":{"set_cold_time":{"cold_time":60000}}}
+
+type coldTimeValue struct {
+	errorCode
+	ColdTime int `json:"cold_time"`
+}
+
+type motionColdTime struct {
+	ColdTime coldTimeValue `json:"set_cold_time"`
+}
+
+type MotionSensorColdTime struct {
+	PIR motionColdTime `json:"smartlife.iot.PIR"`
+}
+
+func (m *MotionSensorColdTime) ErrorCode() int {
+	return m.PIR.ColdTime.ErrorCode
+}
+
+func (m *MotionSensorColdTime) Method() string {
+	return "set_cold_time"
+}
+
+func (m *MotionSensorColdTime) Module() string {
+	return "smartlife.iot.PIR"
+}
+
+func (m *MotionSensorColdTime) SetColdTime(ms int) {
+	m.PIR.ColdTime.ColdTime = ms
+}
+
+// Ambient Light Sensor
+// SEND {"smartlife.iot.LAS":{"get_config":{}}}
+// RECV {"smartlife.iot.LAS":{"get_config":{"devs":[{"hw_id":0,"enable":0,"dark_index":0,"min_adc":0,"max_adc":2450,"level_array":[{"name":"cloudy","adc":490,"value":20},{"name":"overcast","adc":294,"value":12},{"name":"dawn","adc":222,"value":9},{"name":"twilight","adc":222,"value":9},{"name":"total darkness","adc":111,"value":4},{"name":"custom","adc":2400,"value":97}]}],"ver":"1.0","err_code":0}}}
+
+// LightLevel is a brightness threshold. Value is the percentage of the
+// sensor range and Adc the matching raw reading.
+type LightLevel struct {
+	Name  string `json:"name"`
+	Adc   int    `json:"adc"`
+	Value int    `json:"value"`
+}
+
+// AmbientLightConfig is the configuration of the ambient light sensor.
+// DarkIndex selects the level below which the room is considered dark.
+type AmbientLightConfig struct {
+	HardwareId int          `json:"hw_id"`
+	Enable     int          `json:"enable"`
+	DarkIndex  int          `json:"dark_index"`
+	MinAdc     int          `json:"min_adc"`
+	MaxAdc     int          `json:"max_adc"`
+	Levels     []LightLevel `json:"level_array"`
+}
+
+type lightConfigValue struct {
+	errorCode
+	Devices []AmbientLightConfig `json:"devs"`
+	Version string               `json:"ver,omitempty"`
+}
+
+type lightConfig struct {
+	Config lightConfigValue `json:"get_config"`
+}
+
+type AmbientLightSensorConfig struct {
+	LAS lightConfig `json:"smartlife.iot.LAS"`
+}
+
+func (a *AmbientLightSensorConfig) ErrorCode() int {
+	return a.LAS.Config.ErrorCode
+}
+
+func (a *AmbientLightSensorConfig) Method() string {
+	return "get_config"
+}
+
+func (a *AmbientLightSensorConfig) Module() string {
+	return "smartlife.iot.LAS"
+}
+
+func (a *AmbientLightSensorConfig) GetConfig() []AmbientLightConfig {
+	return a.LAS.Config.Devices
+}
+
+// SEND {"smartlife.iot.LAS":{"get_current_brt":{}}}
+// RECV {"smartlife.iot.LAS":{"get_current_brt":{"value":12,"err_code":0}}}
+
+type brightnessValue struct {
+	errorCode
+	Value int `json:"value"`
+}
+
+type lightBrightness struct {
+	Brightness brightnessValue `json:"get_current_brt"`
+}
+
+type AmbientLightSensorBrightness struct {
+	LAS lightBrightness `json:"smartlife.iot.LAS"`
+}
+
+func (a *AmbientLightSensorBrightness) ErrorCode() int {
+	return a.LAS.Brightness.ErrorCode
+}
+
+func (a *AmbientLightSensorBrightness) Method() string {
+	return "get_current_brt"
+}
+
+func (a *AmbientLightSensorBrightness) Module() string {
+	return "smartlife.iot.LAS"
+}
+
+func (a *AmbientLightSensorBrightness) GetBrightness() int {
+	return a.LAS.Brightness.Value
+}
+
+type lightEnable struct {
+	Enable enableValue `json:"set_enable"`
+}
+
+type AmbientLightSensorEnable struct {
+	LAS lightEnable `json:"smartlife.iot.LAS"`
+}
+
+func (a *AmbientLightSensorEnable) ErrorCode() int {
+	return a.LAS.Enable.ErrorCode
+}
+
+func (a *AmbientLightSensorEnable) Method() string {
+	return "set_enable"
+}
+
+func (a *AmbientLightSensorEnable) Module() string {
+	return "smartlife.iot.LAS"
+}
+
+func (a *AmbientLightSensorEnable) SetEnabled(enabled bool) {
+	if enabled {
+		a.LAS.Enable.Enable = 1
+	} else {
+		a.LAS.Enable.Enable = 0
+	}
+}
+
+// SEND {"smartlife.iot.LAS":{"set_brt_level":{"index":5,"value":50}}}
+
+type lightLevel struct {
+	Level levelValue `json:"set_brt_level"`
+}
+
+type AmbientLightSensorLevel struct {
+	LAS lightLevel `json:"smartlife.iot.LAS"`
+}
+
+func (a *AmbientLightSensorLevel) ErrorCode() int {
+	return a.LAS.Level.ErrorCode
+}
+
+func (a *AmbientLightSensorLevel) Method() string {
+	return "set_brt_level"
+}
+
+func (a *AmbientLightSensorLevel) Module() string {
+	return "smartlife.iot.LAS"
+}
+
+func (a *AmbientLightSensorLevel) SetLevel(index int, value int) {
+	a.LAS.Level.Index = index
+	a.LAS.Level.Value = &value
+}
+
+// Light Strip
+// SEND {"smartlife.iot.lighting_effect":{"set_lighting_effect":{"custom":0,"id":"xqUxDhbAhNLqulcuRMyPBmVGyTOyEMEu","brightness":100,"name":"Aurora","segments":[0],"expansion_strategy":1,"enable":1,"type":"sequence","duration":0,"transition":1500,"direction":4,"spread":7,"repeat_times":0,"sequence":[[120,100,100],[240,100,100],[260,100,100],[280,100,100]]}}}
+// SEND {"smartlife.iot.lightStrip":{"set_light_state":{"groups":[[0,4,240,100,100,0],[5,9,0,100,100,0]]}}}
+
+const (
+	lightingEffectModule = "smartlife.iot.lighting_effect"
+	lightStripModule     = "smartlife.iot.lightStrip"
+)
+
+// Effect is a lighting effect of a light strip. Sequence effects cycle
+// through the [hue, saturation, brightness] entries of Sequence, random
+// effects pick colors from the ranges.
+type Effect struct {
+	Id                string  `json:"id"`
+	Name              string  `json:"name"`
+	Custom            int     `json:"custom"`
+	Enable            int     `json:"enable"`
+	Type              string  `json:"type"`
+	Brightness        int     `json:"brightness"`
+	Segments          []int   `json:"segments"`
+	ExpansionStrategy int     `json:"expansion_strategy"`
+	Duration          int     `json:"duration"`
+	Transition        int     `json:"transition"`
+	Direction         int     `json:"direction,omitempty"`
+	Spread            int     `json:"spread,omitempty"`
+	RepeatTimes       int     `json:"repeat_times"`
+	Sequence          [][]int `json:"sequence,omitempty"`
+	HueRange          []int   `json:"hue_range,omitempty"`
+	SaturationRange   []int   `json:"saturation_range,omitempty"`
+	BrightnessRange   []int   `json:"brightness_range,omitempty"`
+	TransitionRange   []int   `json:"transition_range,omitempty"`
+	InitStates        [][]int `json:"init_states,omitempty"`
+	Backgrounds       [][]int `json:"backgrounds,omitempty"`
+	Fadeoff           int     `json:"fadeoff,omitempty"`
+	RandomSeed        int     `json:"random_seed,omitempty"`
+}
+
+type effectValue struct {
+	errorCode
+	Effect
+}
+
+type setLightingEffect struct {
+	Effect effectValue `json:"set_lighting_effect"`
+}
+
+type LightingEffectSet struct {
+	Service setLightingEffect `json:"smartlife.iot.lighting_effect"`
+}
+
+func (l *LightingEffectSet) ErrorCode() int {
+	return l.Service.Effect.ErrorCode
+}
+
+func (l *LightingEffectSet) Method() string {
+	return "set_lighting_effect"
+}
+
+func (l *LightingEffectSet) Module() string {
+	return lightingEffectModule
+}
+
+func (l *LightingEffectSet) SetEffect(e Effect) {
+	l.Service.Effect.Effect = e
+}
+
+// SegmentColor colors the segments First to Last of a light strip. Hue is in
+// degrees, Saturation and Brightness are percentages. A non-zero ColorTemp in
+// Kelvin is used instead of the hue and saturation.
+type SegmentColor struct {
+	First      int
+	Last       int
+	Hue        int
+	Saturation int
+	Brightness int
+	ColorTemp  int
+}
+
+func (c SegmentColor) MarshalJSON() ([]byte, error) {
+	return json.Marshal([6]int{c.First, c.Last, c.Hue, c.Saturation, c.Brightness, c.ColorTemp})
+}
+
+func (c *SegmentColor) UnmarshalJSON(data []byte) error {
+	var v [6]int
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	*c = SegmentColor{v[0], v[1], v[2], v[3], v[4], v[5]}
+	return nil
+}
+
+type segmentsValue struct {
+	errorCode
+	Groups []SegmentColor `json:"groups,omitempty"`
+}
+
+type setSegments struct {
+	Segments segmentsValue `json:"set_light_state"`
+}
+
+type LightStripSegments struct {
+	Service setSegments `json:"smartlife.iot.lightStrip"`
+}
+
+func (l *LightStripSegments) ErrorCode() int {
+	return l.Service.Segments.ErrorCode
+}
+
+func (l *LightStripSegments) Method() string {
+	return "set_light_state"
+}
+
+func (l *LightStripSegments) Module() string {
+	return lightStripModule
+}
+
+func (l *LightStripSegments) SetSegments(segments []SegmentColor) {
+	l.Service.Segments.Groups = segments
+}
+
+// Dimmer
+// SEND {"smartlife.iot.dimmer":{"get_dimmer_parameters":{}}}
+// RECV {"smartlife.iot.dimmer":{"get_dimmer_parameters":{"minThreshold":11,"fadeOnTime":1000,"fadeOffTime":1000,"gentleOnTime":3000,"gentleOffTime":510000,"rampRate":30,"bulb_type":1,"err_code":0}}}
+// SEND {"smartlife.iot.dimmer":{"set_brightness":{"brightness":50}}}
+// SEND {"smartlife.iot.dimmer":{"set_fade_on_time":{"fadeTime":1000}}}
+// SEND {"smartlife.iot.dimmer":{"set_gentle_on_time":{"duration":3000}}}
+// SEND {"smartlife.iot.dimmer":{"set_double_click_action":{"mode":"customize_preset","index":0}}}
+
+const dimmerModule = "smartlife.iot.dimmer"
+
+// DimmerParameters are the fade settings of a dimmer, times are in
+// milliseconds.
+type DimmerParameters struct {
+	MinThreshold  int `json:"minThreshold"`
+	FadeOnTime    int `json:"fadeOnTime"`
+	FadeOffTime   int `json:"fadeOffTime"`
+	GentleOnTime  int `json:"gentleOnTime"`
+	GentleOffTime int `json:"gentleOffTime"`
+	RampRate      int `json:"rampRate"`
+	BulbType      int `json:"bulb_type"`
+}
+
+type dimmerParametersValue struct {
+	errorCode
+	DimmerParameters
+}
+
+type getDimmerParameters struct {
+	Parameters dimmerParametersValue `json:"get_dimmer_parameters"`
+}
+
+type DimmerGetParameters struct {
+	Dimmer getDimmerParameters `json:"smartlife.iot.dimmer"`
+}
+
+func (d *DimmerGetParameters) ErrorCode() int {
+	return d.Dimmer.Parameters.ErrorCode
+}
+
+func (d *DimmerGetParameters) Method() string {
+	return "get_dimmer_parameters"
+}
+
+func (d *DimmerGetParameters) Module() string {
+	return dimmerModule
+}
+
+func (d *DimmerGetParameters) GetParameters() DimmerParameters {
+	return d.Dimmer.Parameters.DimmerParameters
+}
+
+type dimmerBrightnessValue struct {
+	errorCode
+	Brightness int `json:"brightness"`
+}
+
+type setDimmerBrightness struct {
+	Brightness dimmerBrightnessValue `json:"set_brightness"`
+}
+
+type DimmerBrightness struct {
+	Dimmer setDimmerBrightness `json:"smartlife.iot.dimmer"`
+}
+
+func (d *DimmerBrightness) ErrorCode() int {
+	return d.Dimmer.Brightness.ErrorCode
+}
+
+func (d *DimmerBrightness) Method() string {
+	return "set_brightness"
+}
+
+func (d *DimmerBrightness) Module() string {
+	return dimmerModule
+}
+
+func (d *DimmerBrightness) SetBrightness(brightness int) {
+	d.Dimmer.Brightness.Brightness = brightness
+}
+
+type fadeTimeValue struct {
+	errorCode
+	FadeTime int `json:"fadeTime"`
+}
+
+type setFadeOnTime struct {
+	FadeTime fadeTimeValue `json:"set_fade_on_time"`
+}
+
+type DimmerFadeOnTime struct {
+	Dimmer setFadeOnTime `json:"smartlife.iot.dimmer"`
+}
+
+func (d *DimmerFadeOnTime) ErrorCode() int {
+	return d.Dimmer.FadeTime.ErrorCode
+}
+
+func (d *DimmerFadeOnTime) Method() string {
+	return "set_fade_on_time"
+}
+
+func (d *DimmerFadeOnTime) Module() string {
+	return dimmerModule
+}
+
+func (d *DimmerFadeOnTime) SetFadeTime(ms int) {
+	d.Dimmer.FadeTime.FadeTime = ms
+}
+
+type setFadeOffTime struct {
+	FadeTime fadeTimeValue `json:"set_fade_off_time"`
+}
+
+type DimmerFadeOffTime struct {
+	Dimmer setFadeOffTime `json:"smartlife.iot.dimmer"`
+}
+
+func (d *DimmerFadeOffTime) ErrorCode() int {
+	return d.Dimmer.FadeTime.ErrorCode
+}
+
+func (d *DimmerFadeOffTime) Method() string {
+	return "set_fade_off_time"
+}
+
+func (d *DimmerFadeOffTime) Module() string {
+	return dimmerModule
+}
+
+func (d *DimmerFadeOffTime) SetFadeTime(ms int) {
+	d.Dimmer.FadeTime.FadeTime = ms
+}
+
+type durationValue struct {
+	errorCode
+	Duration int `json:"duration"`
+}
+
+type setGentleOnTime struct {
+	GentleTime durationValue `json:"set_gentle_on_time"`
+}
+
+type DimmerGentleOnTime struct {
+	Dimmer setGentleOnTime `json:"smartlife.iot.dimmer"`
+}
+
+func (d *DimmerGentleOnTime) ErrorCode() int {
+	return d.Dimmer.GentleTime.ErrorCode
+}
+
+func (d *DimmerGentleOnTime) Method() string {
+	return "set_gentle_on_time"
+}
+
+func (d *DimmerGentleOnTime) Module() string {
+	return dimmerModule
+}
+
+func (d *DimmerGentleOnTime) SetDuration(ms int) {
+	d.Dimmer.GentleTime.Duration = ms
+}
+
+type setGentleOffTime struct {
+	GentleTime durationValue `json:"set_gentle_off_time"`
+}
+
+type DimmerGentleOffTime struct {
+	Dimmer setGentleOffTime `json:"smartlife.iot.dimmer"`
+}
+
+func (d *DimmerGentleOffTime) ErrorCode() int {
+	return d.Dimmer.GentleTime.ErrorCode
+}
+
+func (d *DimmerGentleOffTime) Method() string {
+	return "set_gentle_off_time"
+}
+
+func (d *DimmerGentleOffTime) Module() string {
+	return dimmerModule
+}
+
+func (d *DimmerGentleOffTime) SetDuration(ms int) {
+	d.Dimmer.GentleTime.Duration = ms
+}
+
+type actionValue struct {
+	errorCode
+	Mode  string `json:"mode"`
+	Index *int   `json:"index,omitempty"`
+}
+
+type setDoubleClickAction struct {
+	Action actionValue `json:"set_double_click_action"`
+}
+
+type DimmerDoubleClickAction struct {
+	Dimmer setDoubleClickAction `json:"smartlife.iot.dimmer"`
+}
+
+func (d *DimmerDoubleClickAction) ErrorCode() int {
+	return d.Dimmer.Action.ErrorCode
+}
+
+func (d *DimmerDoubleClickAction) Method() string {
+	return "set_double_click_action"
+}
+
+func (d *DimmerDoubleClickAction) Module() string {
+	return dimmerModule
+}
+
+func (d *DimmerDoubleClickAction) SetAction(mode string) {
+	d.Dimmer.Action.Mode = mode
+	d.Dimmer.Action.Index = nil
+}
+
+func (d *DimmerDoubleClickAction) SetPreset(mode string, index int) {
+	d.Dimmer.Action.Mode = mode
+	d.Dimmer.Action.Index = &index
+}
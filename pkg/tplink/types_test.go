@@ -307,3 +307,52 @@ func TestUnmarshalSystemInfo(t *testing.T) {
 			generated, expected)
 	}
 }
+
+func TestUnmarshalRealTimeEnergy(t *testing.T) {
+	v1 := "{\"emeter\":{\"get_realtime\":{\"current\":0.012,\"voltage\":121.8,\"power\":0,\"total\":2.5,\"err_code\":0}}}"
+	v2 := "{\"emeter\":{\"get_realtime\":{\"current_ma\":12,\"voltage_mv\":121800,\"power_mw\":0,\"total_wh\":2500,\"err_code\":0}}}"
+
+	for _, data := range []string{v1, v2} {
+		var info ElectricityMeterInfo
+		if err := json.Unmarshal([]byte(data), &info); err != nil {
+			t.Fatalf("failed to unmarshal realtime energy: %s", err)
+		}
+
+		energy := info.Realtime()
+		if !floatCompare(energy.Current, 0.012) || !floatCompare(energy.Voltage, 121.8) ||
+			!floatCompare(energy.Power, 0) || !floatCompare(energy.Total, 2.5) {
+			t.Fatalf("unexpected energy %+v", energy)
+		}
+		if !energy.HasCurrent || !energy.HasVoltage || !energy.HasPower || !energy.HasTotal {
+			t.Fatalf("unexpected presence %+v", energy)
+		}
+	}
+
+	var info ElectricityMeterInfo
+	if err := json.Unmarshal([]byte("{\"emeter\":{\"get_realtime\":{\"power_mw\":5500,\"err_code\":0}}}"), &info); err != nil {
+		t.Fatalf("failed to unmarshal realtime energy: %s", err)
+	}
+	if energy := info.Realtime(); !energy.HasPower || energy.HasTotal || !floatCompare(energy.Power, 5.5) {
+		t.Fatalf("unexpected energy %+v", energy)
+	}
+
+	s, err := json.Marshal(ElectricityMeterInfo{})
+	if err != nil {
+		t.Fatalf("failed to marshal realtime energy: %s", err)
+	}
+	if string(s) != "{\"emeter\":{\"get_realtime\":{}}}" {
+		t.Fatalf("unexpected request '%s'", s)
+	}
+}
+
+func TestUnmarshalDayStat(t *testing.T) {
+	data := "[{\"year\":2021,\"month\":11,\"day\":1,\"energy\":1.172},{\"year\":2021,\"month\":11,\"day\":2,\"energy_wh\":1170}]"
+
+	var stats []DayStat
+	if err := json.Unmarshal([]byte(data), &stats); err != nil {
+		t.Fatalf("failed to unmarshal day stats: %s", err)
+	}
+	if len(stats) != 2 || stats[0].Energy != 1.172 || stats[1].Energy != 1.17 || stats[1].Day != 2 {
+		t.Fatalf("unexpected day stats %+v", stats)
+	}
+}